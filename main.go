@@ -34,17 +34,36 @@ func run() int {
 	}
 
 	options := &parse.Options{
-		Tag:        parse.Tag,
-		Case:       parse.Case,
-		AppendMode: parse.AppendMode,
-		TagMode:    parse.TagMode,
+		Operation:   parse.Operation,
+		RenameKeys:  parse.RenameKeys,
+		DeriveKeys:  parse.DeriveKeys,
+		OptionRules: parse.OptionRules,
+		Embedded:    parse.Embedded,
+		MultiNames:  parse.MultiNames,
+		Tags:        parse.Tags,
+		Tag:         parse.Tags[0],
+		Case:        parse.Case,
+		Cases:       parse.TagCases,
+		Initialisms: parse.Initialisms,
+		Templates:   parse.Templates,
+		Types:       parse.Types,
+		Filename:    parse.Filename,
+		Configs:     parse.Configs,
+		GenerateTag: parse.DefaultGenerateTag,
+		AppendMode:  parse.AppendMode,
+		TagMode:     parse.TagMode,
 		// this is confusing, I'll fix it later when changing documentation/flags behavior
-		DryRun:  !parse.Write,
-		Verbose: parse.Verbose}
+		DryRun:          !parse.Write,
+		Verbose:         parse.Verbose,
+		IncludeTests:    parse.IncludeTests,
+		ContinueOnError: parse.ContinueOnError,
+		Check:           parse.Check,
+		Diff:            parse.FlagDiff,
+		Color:           parse.Color}
 	parse.SetOptions(options)
 	err = parse.AndProcessFiles(flag.Args())
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		return -1
 	}
 	return 0
@@ -52,6 +71,8 @@ func run() int {
 
 func usage() {
 	fmt.Fprintln(os.Stderr, "usage: st [flags] [path ...]")
+	fmt.Fprintln(os.Stderr, "       st untag [flags] [path ...]")
+	fmt.Fprintln(os.Stderr, "       st [flags] [-filename name] - < file.go")
 	flag.PrintDefaults()
 	exit(-2)
 }
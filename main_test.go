@@ -66,19 +66,62 @@ func TestRun(t *testing.T) {
 			f, err := ioutil.TempFile(tempDir, "")
 			So(err, ShouldBeNil)
 
-			parse.SetArgs([]string{"-s", ""})
+			parse.SetArgs([]string{"-case=snake", ""})
 			i := run()
 
 			So(err, ShouldBeNil)
 			So(i, ShouldEqual, -1)
 
 			f.WriteString(testData)
-			parse.SetArgs([]string{"-s", "-w", f.Name()})
+			parse.SetArgs([]string{"-case=snake", "-w", f.Name()})
 			i = run()
 			So(i, ShouldEqual, 0)
 			data, err := ioutil.ReadFile(f.Name())
 			So(err, ShouldBeNil)
 			So(string(data), ShouldEqual, expectedWrittenData)
+
+			Convey("Check mode returns -1 for an untagged file and 0 once it is tagged", func() {
+				untagged, err := ioutil.TempFile(tempDir, "")
+				So(err, ShouldBeNil)
+				untagged.WriteString(testData)
+				parse.SetArgs([]string{"-check", untagged.Name()})
+				So(run(), ShouldEqual, -1)
+				parse.SetArgs([]string{"-check", f.Name()})
+				So(run(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("Given a file with a comment directive", func() {
+			f, err := ioutil.TempFile(tempDir, "")
+			So(err, ShouldBeNil)
+			f.WriteString("package test\n\n//@st -t=yaml\ntype User struct {\n\tUserID int\n}\n")
+			f.Close()
+
+			Convey("The directive is applied", func() {
+				parse.SetArgs([]string{"-w", f.Name()})
+				So(run(), ShouldEqual, 0)
+				data, err := ioutil.ReadFile(f.Name())
+				So(err, ShouldBeNil)
+				So(string(data), ShouldContainSubstring, "`yaml:\"user_id\"`")
+				So(string(data), ShouldNotContainSubstring, "json")
+			})
+
+			Convey("A malformed directive is an error", func() {
+				err := ioutil.WriteFile(f.Name(), []byte("package test\n\n//@st -t=\ntype User struct {\n\tUserID int\n}\n"), 0664)
+				So(err, ShouldBeNil)
+				tempStderr, err := ioutil.TempFile(tempDir, "stderr")
+				So(err, ShouldBeNil)
+				oldStderr := os.Stderr
+				os.Stderr = tempStderr
+				parse.SetArgs([]string{"-w", f.Name()})
+				i := run()
+				os.Stderr = oldStderr
+				tempStderr.Close()
+				So(i, ShouldEqual, -1)
+				data, err := ioutil.ReadFile(tempStderr.Name())
+				So(err, ShouldBeNil)
+				So(string(data), ShouldContainSubstring, f.Name()+":3:1: Invalid directive")
+			})
 		})
 	})
 }
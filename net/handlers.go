@@ -45,12 +45,10 @@ func processStructTagRequest(req *http.Request) (data []byte, err error) {
 	opts := parse.DefaultOptions()
 	opts.Case = str.Case
 	opts.Tag = str.TagName
-	//not thread safe, will need a context in the future
-	parse.SetOptions(opts)
 	data = []byte(str.Message)
 	// naive implementation for first version, we should use the ast in the final version.
 	if !strings.Contains(string(data), "package") {
 		data = parse.Insert(data, []byte("package st\n"), 0)
 	}
-	return parse.ProcessBytes(data, "st.go")
+	return parse.NewTagger(opts, parse.Filters{}).ProcessBytes(data, "st.go")
 }
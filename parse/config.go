@@ -0,0 +1,372 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alistanis/st/sterrors"
+)
+
+// ConfigFileName is the name of the project configuration file, which is looked for in the directory being tagged and
+// every directory above it
+const ConfigFileName = ".st.json"
+
+// Config is a project configuration file. Each setting is used as the value of the command line flag of the same name
+// when that flag is not given, so flags always take precedence. Packages holds settings for the packages below the
+// directory of the config file, keyed by their slash separated path relative to it, which are used over the settings
+// above them when tagging that package or a package below it. Presets defines presets, which are used over the
+// DefaultPresets of the same name.
+type Config struct {
+	Tags           []string           `json:"tags"`
+	Case           string             `json:"case"`
+	Initialisms    []string           `json:"initialisms"`
+	IgnoredFields  []string           `json:"ignored-fields"`
+	IgnoredStructs []string           `json:"ignored-structs"`
+	OnlyStructs    []string           `json:"only-structs"`
+	Skip           []string           `json:"skip"`
+	Only           []string           `json:"only"`
+	Embedded       string             `json:"embedded"`
+	Multi          string             `json:"multi"`
+	Opts           []string           `json:"opts"`
+	Templates      []string           `json:"templates"`
+	Preset         []string           `json:"preset"`
+	Presets        map[string]*Config `json:"presets"`
+	Packages       map[string]*Config `json:"packages"`
+}
+
+// flagAliases maps the short names of flags that a Config can set to their long names
+var flagAliases = map[string]string{
+	"t":  "tag-name",
+	"i":  "ignored-fields",
+	"is": "ignored-structs",
+}
+
+// FindConfig looks for ConfigFileName in dir and every directory above it, and returns the path of the first one found,
+// or "" if there is none
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ConfigFileName)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadConfig reads the config file at path. Unknown settings are errors, so that misspelled settings are not ignored.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, sterrors.ErrInvalidConfig(path, err.Error())
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	c := &Config{}
+	if err := dec.Decode(c); err != nil {
+		return nil, sterrors.ErrInvalidConfig(path, err.Error())
+	}
+	return c, nil
+}
+
+// FlagValues returns the values of the command line flags that the config sets for the package in pkg, the slash
+// separated path of the package relative to the directory of the config file. Settings for the package, and for the
+// packages above it, are used over the settings above them.
+func (c *Config) FlagValues(pkg string) map[string][]string {
+	values := c.flagValues()
+	var paths []string
+	for p := range c.Packages {
+		paths = append(paths, p)
+	}
+	// shorter paths are above longer ones, so their settings are replaced by those of the longer ones
+	sort.Slice(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+	for _, p := range paths {
+		if clean := strings.Trim(p, "/"); pkg == clean || strings.HasPrefix(pkg, clean+"/") {
+			for name, v := range c.Packages[p].flagValues() {
+				values[name] = v
+			}
+		}
+	}
+	return values
+}
+
+// flagValues returns the values of the command line flags for the settings that are set, ignoring Packages
+func (c *Config) flagValues() map[string][]string {
+	values := make(map[string][]string)
+	set := func(name string, v ...string) {
+		if len(v) > 0 && v[0] != "" {
+			values[name] = v
+		}
+	}
+	set("tag-name", strings.Join(c.Tags, ","))
+	set("case", c.Case)
+	set("initialisms", strings.Join(c.Initialisms, ","))
+	set("ignored-fields", strings.Join(c.IgnoredFields, ","))
+	set("ignored-structs", strings.Join(c.IgnoredStructs, ","))
+	set("only-structs", strings.Join(c.OnlyStructs, ","))
+	set("skip", strings.Join(c.Skip, ","))
+	set("only", strings.Join(c.Only, ","))
+	set("embedded", c.Embedded)
+	set("multi", c.Multi)
+	set("opts", strings.Join(c.Opts, ","))
+	set("template", c.Templates...)
+	set("preset", strings.Join(c.Preset, ","))
+	return values
+}
+
+// tagModeFlags are the flags that set the TagMode. When any of them is given on the command line, the config file and
+// presets give none of them, since some cannot be combined and the flags would not otherwise take precedence.
+var tagModeFlags = []string{"ignored-structs", "only-structs", "skip", "only"}
+
+// ConfigSet resolves the settings that config files and presets give for each directory that is tagged, since the
+// Packages of a config file can give each package its own settings. Settings are only used for the flags in Given,
+// the long names of the flags that were given on the command line, when they are not there, so flags always take
+// precedence. Path is the config file to use for every directory, and if it is empty ConfigFileName is looked for in
+// each directory and every directory above it. Presets is the comma separated list of presets given with -preset,
+// which is used over the preset setting of the config file.
+type ConfigSet struct {
+	Path    string
+	Presets string
+	Given   map[string]bool
+
+	mu    sync.Mutex
+	cache map[string]configValues
+}
+
+// configValues are the flag values for one directory, along with the path of the config file they came from
+type configValues struct {
+	values map[string][]string
+	path   string
+}
+
+// NewConfigSet returns a new *ConfigSet
+func NewConfigSet(path, presets string, given map[string]bool) *ConfigSet {
+	return &ConfigSet{Path: path, Presets: presets, Given: given, cache: make(map[string]configValues)}
+}
+
+// Values returns the values of the command line flags that the config file and presets give for the directory dir,
+// leaving out the flags that were given, along with the path of the config file that was used, or "" if there was none
+func (s *ConfigSet) Values(dir string) (map[string][]string, string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cv, ok := s.cache[abs]; ok {
+		return cv.values, cv.path, nil
+	}
+
+	path := s.Path
+	if path == "" {
+		if path, err = FindConfig(abs); err != nil {
+			return nil, "", err
+		}
+	}
+	c, pkg := &Config{}, "."
+	if path != "" {
+		if c, err = LoadConfig(path); err != nil {
+			return nil, path, err
+		}
+		sterrors.Printf("Using config file %s for %s\n", path, dir)
+		if root, err := filepath.Abs(filepath.Dir(path)); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil {
+				pkg = filepath.ToSlash(rel)
+			}
+		}
+	}
+
+	values := c.FlagValues(pkg)
+	presets := s.Presets
+	if !s.Given["preset"] && len(values["preset"]) > 0 {
+		presets = values["preset"][0]
+	}
+	delete(values, "preset")
+	preset, err := c.CombinePresets(splitList(presets)...)
+	if err != nil {
+		return nil, path, err
+	}
+	for name, v := range preset.flagValues() {
+		if _, ok := values[name]; !ok {
+			values[name] = v
+		}
+	}
+
+	for name := range values {
+		if s.Given[name] {
+			delete(values, name)
+		}
+	}
+	for _, name := range tagModeFlags {
+		if s.Given[name] {
+			for _, other := range tagModeFlags {
+				delete(values, other)
+			}
+			break
+		}
+	}
+	s.cache[abs] = configValues{values: values, path: path}
+	return values, path, nil
+}
+
+// Tagger returns a copy of t with the settings for the directory dir applied, or t if there are none
+func (s *ConfigSet) Tagger(t *Tagger, dir string) (*Tagger, error) {
+	values, path, err := s.Values(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return t, nil
+	}
+	configured, err := applyFlagValues(t, values)
+	if err != nil {
+		if path == "" {
+			return nil, err
+		}
+		return nil, sterrors.ErrInvalidConfig(path, err.Error())
+	}
+	return configured, nil
+}
+
+// applyFlagValues returns a copy of t with the values of command line flags, keyed by their long names, applied to
+// its Options and Filters the same way as the flags themselves
+func applyFlagValues(t *Tagger, values map[string][]string) (*Tagger, error) {
+	o, f := *t.Options, t.Filters
+	value := func(name string) (string, bool) {
+		v, ok := values[name]
+		if !ok || len(v) == 0 {
+			return "", false
+		}
+		return v[len(v)-1], true
+	}
+
+	var err error
+	var ok bool
+	if v, given := value("tag-name"); given {
+		if o.Tags, o.Cases, err = parseTags(v); err != nil {
+			return nil, err
+		}
+		o.Tag = o.Tags[0]
+	}
+	if v, given := value("case"); given {
+		if !IsValidCase(v) {
+			return nil, sterrors.ErrUnknownCase(v)
+		}
+		o.Case = v
+	}
+	if v, given := value("initialisms"); given {
+		o.Initialisms = splitList(v)
+	}
+	if v, given := value("ignored-fields"); given {
+		f.IgnoredFields = splitList(v)
+	}
+	if v, given := value("embedded"); given {
+		if o.Embedded, ok = EmbeddedPolicies[v]; !ok {
+			return nil, sterrors.ErrUnknownEmbeddedPolicy(v)
+		}
+	}
+	if v, given := value("multi"); given {
+		if o.MultiNames, ok = MultiNamePolicies[v]; !ok {
+			return nil, sterrors.ErrUnknownMultiNamePolicy(v)
+		}
+	}
+	if v, given := value("opts"); given {
+		if o.OptionRules, err = parseOptionRules(v); err != nil {
+			return nil, err
+		}
+	}
+	if vs, given := values["template"]; given {
+		if o.Templates, err = parseTemplates(vs); err != nil {
+			return nil, err
+		}
+	}
+
+	var set []string
+	for _, name := range tagModeFlags[1:] {
+		if _, given := value(name); given {
+			set = append(set, name)
+		}
+	}
+	if len(set) > 1 {
+		return nil, sterrors.ErrMutuallyExclusiveParameters(set[0], set[1])
+	}
+	if v, given := value("ignored-structs"); given {
+		f.IgnoredStructs = splitList(v)
+		o.TagMode = SkipSpecifiedStructs
+	}
+	if v, given := value("only-structs"); given {
+		f.IncludedStructs = splitList(v)
+		o.TagMode = IncludeSpecifiedStructs
+	}
+	if v, given := value("skip"); given {
+		f.SkippedKeypairs = splitList(v)
+		o.TagMode = SkipStructAndFieldKeypairs
+		if err := verifyKeypairs(f.SkippedKeypairs); err != nil {
+			return nil, err
+		}
+	}
+	if v, given := value("only"); given {
+		f.IncludedKeypairs = splitList(v)
+		o.TagMode = IncludeStructAndFieldKeypairs
+		if err := verifyKeypairs(f.IncludedKeypairs); err != nil {
+			return nil, err
+		}
+	}
+
+	configured := *t
+	configured.Options, configured.Filters = &o, f
+	return &configured, nil
+}
+
+// configure sets Configs from the flags that were given, and checks the config file and presets for the first path so
+// that mistakes in them are reported before anything is tagged
+func configure() error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+		if long, ok := flagAliases[f.Name]; ok {
+			given[long] = true
+		}
+	})
+	presets := ""
+	if given["preset"] {
+		presets = PresetString
+	}
+	Configs = NewConfigSet(ConfigPath, presets, given)
+
+	target := flag.Arg(0)
+	if target == StdinPath {
+		target = Filename
+	}
+	_, err := Configs.Tagger(NewTagger(nil, Filters{}), targetDir(target))
+	return err
+}
+
+// targetDir returns the directory that is being tagged for the path p, as given on the command line
+func targetDir(p string) string {
+	p = strings.TrimSuffix(p, RecursiveSuffix)
+	if p == "" {
+		return "."
+	}
+	if strings.ContainsAny(p, "*?[") {
+		return filepath.Dir(p)
+	}
+	if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+		return p
+	}
+	return filepath.Dir(p)
+}
@@ -0,0 +1,155 @@
+package parse
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfig(t *testing.T) {
+	Convey("Given a project with a config file", t, func() {
+		root, err := ioutil.TempDir(tempDir, "config")
+		So(err, ShouldBeNil)
+		for _, dir := range []string{"models/db", "cmd"} {
+			err = os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755)
+			So(err, ShouldBeNil)
+		}
+		config := filepath.Join(root, ConfigFileName)
+		err = ioutil.WriteFile(config, []byte(`{
+	"tags": ["json=camel", "yaml"],
+	"case": "snake",
+	"ignored-fields": ["Password"],
+	"templates": ["gorm:\"column:{{.Snake}}\""],
+	"packages": {
+		"models": {"tags": ["json", "db"], "ignored-structs": ["Secret"]},
+		"models/db": {"case": "kebab"}
+	}
+}`), 0664)
+		So(err, ShouldBeNil)
+
+		Convey("We can find it from the directories below it", func() {
+			for _, dir := range []string{root, filepath.Join(root, "models", "db"), filepath.Join(root, "cmd")} {
+				path, err := FindConfig(dir)
+				So(err, ShouldBeNil)
+				So(path, ShouldEqual, config)
+			}
+		})
+
+		Convey("We can load it", func() {
+			c, err := LoadConfig(config)
+			So(err, ShouldBeNil)
+			So(c.Tags, ShouldResemble, []string{"json=camel", "yaml"})
+			So(len(c.Packages), ShouldEqual, 2)
+
+			Convey("Package settings are used over the settings above them", func() {
+				So(c.FlagValues("."), ShouldResemble, map[string][]string{
+					"tag-name":       {"json=camel,yaml"},
+					"case":           {"snake"},
+					"ignored-fields": {"Password"},
+					"template":       {`gorm:"column:{{.Snake}}"`},
+				})
+				values := c.FlagValues("models/db")
+				So(values["tag-name"], ShouldResemble, []string{"json,db"})
+				So(values["case"], ShouldResemble, []string{"kebab"})
+				So(values["ignored-structs"], ShouldResemble, []string{"Secret"})
+				So(values["ignored-fields"], ShouldResemble, []string{"Password"})
+				So(c.FlagValues("modelsdb")["tag-name"], ShouldResemble, []string{"json=camel,yaml"})
+			})
+		})
+
+		Convey("Its settings are used for the flags that are not given", func() {
+			SetArgs([]string{filepath.Join(root, "models", RecursiveSuffix)})
+			err := Flags()
+			So(err, ShouldBeNil)
+			tagger := flagTagger(filepath.Join(root, "models"))
+			So(tagger.Options.Tags, ShouldResemble, []string{"json", "db"})
+			So(tagger.Filters.IgnoredStructs, ShouldResemble, []string{"Secret"})
+			So(tagger.Options.TagMode, ShouldEqual, SkipSpecifiedStructs)
+			So(len(tagger.Options.Templates), ShouldEqual, 1)
+
+			SetArgs([]string{"-t=xml", "-case=camel", filepath.Join(root, "models", "db")})
+			err = Flags()
+			So(err, ShouldBeNil)
+			tagger = flagTagger(filepath.Join(root, "models", "db"))
+			So(tagger.Options.Tags, ShouldResemble, []string{"xml"})
+			So(tagger.Options.Case, ShouldEqual, Camel)
+
+			SetArgs([]string{"-template=db:\"{{.Lower}}\"", filepath.Join(root, "cmd")})
+			err = Flags()
+			So(err, ShouldBeNil)
+			tagger = flagTagger(filepath.Join(root, "cmd"))
+			So(tagger.Options.Tags, ShouldResemble, []string{"json", "yaml"})
+			So(tagger.Options.Cases, ShouldResemble, map[string]string{"json": Camel})
+			So(len(tagger.Options.Templates), ShouldEqual, 1)
+			So(tagger.Options.Templates[0].Key, ShouldEqual, "db")
+		})
+
+		Convey("Package settings are used for the files in each package", func() {
+			for _, dir := range []string{root, filepath.Join(root, "models", "db")} {
+				err := ioutil.WriteFile(filepath.Join(dir, "user.go"), []byte("package test\n\ntype User struct {\n\tUserID int\n}\n"), 0664)
+				So(err, ShouldBeNil)
+			}
+			SetArgs([]string{"-w", "-t=json", filepath.Join(root, RecursiveSuffix)})
+			err := Flags()
+			So(err, ShouldBeNil)
+			o := DefaultOptions()
+			o.Tags, o.DryRun, o.Configs = Tags, false, Configs
+			err = NewTagger(o, Filters{}).AndProcessFiles(flag.Args())
+			So(err, ShouldBeNil)
+
+			data, err := ioutil.ReadFile(filepath.Join(root, "user.go"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "`json:\"user_id\" gorm:\"column:user_id\"`")
+			data, err = ioutil.ReadFile(filepath.Join(root, "models", "db", "user.go"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "`json:\"user-id\" gorm:\"column:user_id\"`")
+		})
+
+		Convey("Tag mode flags that are given are used over all of its tag mode settings", func() {
+			err := ioutil.WriteFile(config, []byte(`{"only-structs": ["User"]}`), 0664)
+			So(err, ShouldBeNil)
+			SetArgs([]string{"-skip=User.Password", root})
+			err = Flags()
+			So(err, ShouldBeNil)
+			tagger := flagTagger(root)
+			So(tagger.Options.TagMode, ShouldEqual, SkipStructAndFieldKeypairs)
+			So(tagger.Filters.IncludedStructs, ShouldBeEmpty)
+		})
+
+		Convey("Invalid config files are errors", func() {
+			err := ioutil.WriteFile(config, []byte(`{"tag": ["json"]}`), 0664)
+			So(err, ShouldBeNil)
+			_, err = LoadConfig(config)
+			So(err, ShouldNotBeNil)
+
+			SetArgs([]string{root})
+			err = Flags()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("We can give the config file explicitly", func() {
+			other := filepath.Join(tempDir, "other.json")
+			err := ioutil.WriteFile(other, []byte(`{"case": "pascal"}`), 0664)
+			So(err, ShouldBeNil)
+			SetArgs([]string{"-config=" + other, root})
+			err = Flags()
+			So(err, ShouldBeNil)
+			tagger := flagTagger(root)
+			So(tagger.Options.Case, ShouldEqual, Pascal)
+			So(tagger.Options.Tags, ShouldResemble, []string{DefaultTag})
+		})
+	})
+}
+
+// flagTagger returns the Tagger for the flags that were given, with the settings that Configs gives for dir applied
+func flagTagger(dir string) *Tagger {
+	o := &Options{Tags: Tags, Tag: Tags[0], Cases: TagCases, Case: Case, Embedded: Embedded, MultiNames: MultiNames,
+		OptionRules: OptionRules, Templates: Templates, TagMode: TagMode}
+	tagger, err := Configs.Tagger(NewTagger(o, Filters{IgnoredStructs: IgnoredStructs}), dir)
+	So(err, ShouldBeNil)
+	return tagger
+}
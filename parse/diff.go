@@ -0,0 +1,261 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DiffContext is the number of unchanged lines shown around each change in a diff
+const DiffContext = 3
+
+// ANSI escape codes used to color diffs
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// noNewline marks a line that is missing its newline at the end of a file
+const noNewline = "\\ No newline at end of file\n"
+
+// diff operations
+const (
+	opEqual = iota
+	opDelete
+	opInsert
+)
+
+// diffLine is a single line of a diff and the operation that produced it
+type diffLine struct {
+	op   int
+	text string
+}
+
+// Diff returns a unified diff between the original and tagged bytes of the file name, in the same format as gofmt -d.
+// If color is true, the diff is colored with ANSI escape codes. Diff returns nil if there are no differences.
+func Diff(name string, original, tagged []byte, color bool) []byte {
+	lines := diffLines(splitLines(string(original)), splitLines(string(tagged)))
+	hunks := groupHunks(lines, DiffContext)
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	paint := func(c, s string) string {
+		if !color {
+			return s
+		}
+		return c + strings.TrimSuffix(s, "\n") + colorReset + "\n"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(paint(colorBold, fmt.Sprintf("--- %s.orig\n", name)))
+	buf.WriteString(paint(colorBold, fmt.Sprintf("+++ %s\n", name)))
+	for _, h := range hunks {
+		buf.WriteString(paint(colorCyan, h.header()))
+		for _, l := range h.lines {
+			prefix, c := " ", ""
+			switch l.op {
+			case opDelete:
+				prefix, c = "-", colorRed
+			case opInsert:
+				prefix, c = "+", colorGreen
+			}
+			text := l.text
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n" + noNewline
+			}
+			if c != "" {
+				text = paint(c, prefix+text)
+			} else {
+				text = prefix + text
+			}
+			buf.WriteString(text)
+		}
+	}
+	return buf.Bytes()
+}
+
+// splitLines splits s into lines, keeping the newline at the end of each line
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script that turns a into b, using the linear space version of the Myers diff
+// algorithm, so that diffing a large file with many changes does not need memory for every step of the search
+func diffLines(a, b []string) []diffLine {
+	var script []diffLine
+	compareLines(a, b, &script)
+	return script
+}
+
+// compareLines appends the edit script that turns a into b to script. Lines that a and b start and end with are equal,
+// and what is left is split at the middle snake of its shortest edit script, with each half compared on its own.
+func compareLines(a, b []string, script *[]diffLine) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		*script = append(*script, diffLine{op: opEqual, text: a[0]})
+		a, b = a[1:], b[1:]
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, l := range b {
+			*script = append(*script, diffLine{op: opInsert, text: l})
+		}
+	case len(b) == 0:
+		for _, l := range a {
+			*script = append(*script, diffLine{op: opDelete, text: l})
+		}
+	default:
+		// with no common first or last line, at least two edits are needed, so both halves are smaller than a and b
+		x, y, u, v := middleSnake(a, b)
+		compareLines(a[:x], b[:y], script)
+		for _, l := range a[x:u] {
+			*script = append(*script, diffLine{op: opEqual, text: l})
+		}
+		compareLines(a[u:], b[v:], script)
+	}
+
+	for _, l := range common {
+		*script = append(*script, diffLine{op: opEqual, text: l})
+	}
+}
+
+// middleSnake searches for the shortest edit script that turns a into b from both ends at once, and returns the snake
+// (a run of equal lines, which may be empty) from (x, y) to (u, v) where the two searches meet. Only the furthest
+// reaching point of each diagonal is kept for each search, so the memory used is linear in the length of a and b.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	max := (n+m+1)/2 + 1
+	delta := n - m
+	odd := delta%2 != 0
+	// forward[max+k] is the furthest x reached on the diagonal k = x-y from the start, and backward[max+k] is the
+	// furthest distance from the end reached on the diagonal k = (n-x)-(m-y)
+	forward, backward := make([]int, 2*max+1), make([]int, 2*max+1)
+
+	for d := 0; d < max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x0 int
+			if k == -d || (k != d && forward[max+k-1] < forward[max+k+1]) {
+				x0 = forward[max+k+1]
+			} else {
+				x0 = forward[max+k-1] + 1
+			}
+			x, y := x0, x0-k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[max+k] = x
+			if back := delta - k; odd && back >= -(d-1) && back <= d-1 && x+backward[max+back] >= n {
+				return x0, x0 - k, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x0 int
+			if k == -d || (k != d && backward[max+k-1] < backward[max+k+1]) {
+				x0 = backward[max+k+1]
+			} else {
+				x0 = backward[max+k-1] + 1
+			}
+			x, y := x0, x0-k
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[max+k] = x
+			if front := delta - k; !odd && front >= -d && front <= d && x+forward[max+front] >= n {
+				return n - x, m - y, n - x0, m - (x0 - k)
+			}
+		}
+	}
+	// the searches always meet before max, since the edit script is no longer than n+m, but deleting all of a and
+	// inserting all of b is still a correct script
+	return n, 0, n, 0
+}
+
+// hunk is a group of changed lines and the unchanged lines around them
+type hunk struct {
+	aStart, aCount int
+	bStart, bCount int
+	lines          []diffLine
+}
+
+// header returns the @@ line for the hunk
+func (h *hunk) header() string {
+	aStart, bStart := h.aStart, h.bStart
+	// an empty range starts at the line before it, as in diff -u
+	if h.aCount == 0 {
+		aStart--
+	}
+	if h.bCount == 0 {
+		bStart--
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aStart, h.aCount, bStart, h.bCount)
+}
+
+// groupHunks groups an edit script into hunks, with context unchanged lines around each change. Changes that are close
+// enough for their context to touch are placed in the same hunk.
+func groupHunks(lines []diffLine, context int) []*hunk {
+	// aLine[i] and bLine[i] are the line numbers of lines[i] in a and b
+	aLine, bLine := make([]int, len(lines)), make([]int, len(lines))
+	ai, bi := 1, 1
+	for i, l := range lines {
+		aLine[i], bLine[i] = ai, bi
+		if l.op != opInsert {
+			ai++
+		}
+		if l.op != opDelete {
+			bi++
+		}
+	}
+
+	var hunks []*hunk
+	for i := 0; i < len(lines); {
+		if lines[i].op == opEqual {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op != opEqual {
+				end = j
+			} else if j-end > 2*context {
+				break
+			}
+		}
+		stop := end + context + 1
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		h := &hunk{aStart: aLine[start], bStart: bLine[start], lines: lines[start:stop]}
+		for _, l := range h.lines {
+			if l.op != opInsert {
+				h.aCount++
+			}
+			if l.op != opDelete {
+				h.bCount++
+			}
+		}
+		hunks = append(hunks, h)
+		i = stop
+	}
+	return hunks
+}
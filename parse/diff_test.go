@@ -0,0 +1,159 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiff(t *testing.T) {
+	Convey("Given the original and tagged source of a file", t, func() {
+		tagged, err := ProcessBytes([]byte(snakeTestDataExistingTags), "test.go")
+		So(err, ShouldBeNil)
+
+		Convey("There is no diff when nothing changed", func() {
+			So(Diff("test.go", tagged, tagged, false), ShouldBeNil)
+		})
+
+		Convey("We get a unified diff of the lines that changed", func() {
+			original := "package test\n\ntype TestStruct struct {\n\tField string\n}\n"
+			diff := Diff("test.go", []byte(original), []byte(expectedWrittenData), false)
+			So(string(diff), ShouldEqual, strings.Replace(`--- test.go.orig
++++ test.go
+@@ -1,5 +1,5 @@
+ package test
+ 
+ type TestStruct struct {
+-	Field string
++	Field string %sjson:"field"%s
+ }
+`, "%s", "`", -1))
+		})
+
+		Convey("Changes that are far apart are placed in separate hunks", func() {
+			a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+			b := "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"
+			So(string(Diff("n", []byte(a), []byte(b), false)), ShouldEqual, `--- n.orig
++++ n
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -7,4 +7,4 @@
+ 7
+ 8
+ 9
+-10
++ten
+`)
+		})
+
+		Convey("A missing newline at the end of the file is marked", func() {
+			So(string(Diff("n", []byte("a"), []byte("a\n"), false)), ShouldEqual, `--- n.orig
++++ n
+@@ -1,1 +1,1 @@
+-a
+\ No newline at end of file
++a
+`)
+		})
+
+		Convey("We can color the diff", func() {
+			diff := string(Diff("n", []byte("a\n"), []byte("b\n"), true))
+			So(diff, ShouldContainSubstring, colorRed+"-a"+colorReset)
+			So(diff, ShouldContainSubstring, colorGreen+"+b"+colorReset)
+		})
+	})
+
+	Convey("Edit scripts are the shortest that turn one file into the other", t, func() {
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 500; i++ {
+			a, b := randomLines(r, r.Intn(12)), randomLines(r, r.Intn(12))
+			script := diffLines(a, b)
+			var gotA, gotB []string
+			edits := 0
+			for _, l := range script {
+				if l.op != opInsert {
+					gotA = append(gotA, l.text)
+				}
+				if l.op != opDelete {
+					gotB = append(gotB, l.text)
+				}
+				if l.op != opEqual {
+					edits++
+				}
+			}
+			So(strings.Join(gotA, ""), ShouldEqual, strings.Join(a, ""))
+			So(strings.Join(gotB, ""), ShouldEqual, strings.Join(b, ""))
+			So(edits, ShouldEqual, len(a)+len(b)-2*lcsLength(a, b))
+		}
+	})
+
+	Convey("Diffing a large struct where every field changes uses memory linear in its size", t, func() {
+		original, tagged := largeStruct(4000)
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		diff := Diff("large.go", original, tagged, false)
+		runtime.ReadMemStats(&after)
+		So(strings.Count(string(diff), "\n+\t"), ShouldEqual, 4000)
+		So(after.TotalAlloc-before.TotalAlloc, ShouldBeLessThan, 64<<20)
+	})
+}
+
+func BenchmarkDiffLargeStruct(b *testing.B) {
+	original, tagged := largeStruct(4000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Diff("large.go", original, tagged, false)
+	}
+}
+
+// largeStruct returns the source of a struct with n fields, and the same source with every field tagged
+func largeStruct(n int) ([]byte, []byte) {
+	var original, tagged bytes.Buffer
+	original.WriteString("package test\n\ntype Large struct {\n")
+	tagged.WriteString("package test\n\ntype Large struct {\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&original, "\tField%d string\n", i)
+		fmt.Fprintf(&tagged, "\tField%d string `json:\"field%d\"`\n", i, i)
+	}
+	original.WriteString("}\n")
+	tagged.WriteString("}\n")
+	return original.Bytes(), tagged.Bytes()
+}
+
+// randomLines returns n lines drawn from a small alphabet, so that lines repeat often
+func randomLines(r *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a'+r.Intn(4))) + "\n"
+	}
+	return lines
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b
+func lcsLength(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,325 @@
+package parse
+
+import (
+	"errors"
+	"flag"
+	"go/ast"
+	"io/ioutil"
+	"strings"
+	"unicode"
+
+	"github.com/alistanis/st/sterrors"
+)
+
+// CommentDirective represents a comment with //@st at its beginning, placed above a type declaration to override
+// options for the structs declared in it, as in //@st -t=yaml -case=camel. Its arguments are parsed like command line
+// flags, but into their own FlagSet, so no global state is changed.
+// I am really not a fan of treating comments as anything more than a comment, but Go unfortunately has no other constructs
+type CommentDirective struct {
+	BaseText string
+	FlagSet  *flag.FlagSet
+
+	tag           string
+	caseName      string
+	ignoredFields string
+	initialisms   string
+	embedded      string
+	multi         string
+	opts          string
+	templates     stringList
+	appendMode    bool
+	overwrite     bool
+	merge         bool
+}
+
+// Args returns the underlying FlagSet.Args()
+func (c *CommentDirective) Args() []string {
+	return c.FlagSet.Args()
+}
+
+// IsCommentDirective checks if the text of a comment is a directive for the generate tag, such as //@st -t=yaml. Since
+// gofmt adds a space after the slashes of doc comments that do not look like //go: directives, // @st -t=yaml is a
+// directive as well.
+func IsCommentDirective(text, generateTag string) bool {
+	text = strings.TrimLeftFunc(strings.TrimPrefix(text, "//"), unicode.IsSpace)
+	if generateTag == "" || !strings.HasPrefix(text, generateTag) {
+		return false
+	}
+	rest := strings.TrimPrefix(text, generateTag)
+	return rest == "" || unicode.IsSpace([]rune(rest)[0])
+}
+
+// NewCommentDirective takes the text of a comment directive, such as //@st -t=yaml -case=camel, and parses the flags
+// after the generate tag into a new FlagSet. Flags may be quoted as they would be in a shell, as in
+// -template='gorm:"column:{{.Snake}}"'. The directive may give -t/-tag-name, -case, -i/-ignored-fields, -initialisms,
+// -a/-append, -o/-overwrite, -m/-merge, -embedded, -multi, -opts and -template; anything else, including paths, is an
+// error.
+func NewCommentDirective(s string) (*CommentDirective, error) {
+	cd := &CommentDirective{BaseText: s, FlagSet: flag.NewFlagSet(s, flag.ContinueOnError)}
+	fs := cd.FlagSet
+	// flag.ContinueOnError still prints usage, which would be noise in the middle of tagging
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVar(&cd.tag, "t", "", "")
+	fs.StringVar(&cd.tag, "tag-name", "", "")
+	fs.StringVar(&cd.caseName, "case", "", "")
+	fs.StringVar(&cd.ignoredFields, "i", "", "")
+	fs.StringVar(&cd.ignoredFields, "ignored-fields", "", "")
+	fs.StringVar(&cd.initialisms, "initialisms", "", "")
+	fs.StringVar(&cd.embedded, "embedded", "", "")
+	fs.StringVar(&cd.multi, "multi", "", "")
+	fs.StringVar(&cd.opts, "opts", "", "")
+	fs.Var(&cd.templates, "template", "")
+	fs.BoolVar(&cd.appendMode, "a", false, "")
+	fs.BoolVar(&cd.appendMode, "append", false, "")
+	fs.BoolVar(&cd.overwrite, "o", false, "")
+	fs.BoolVar(&cd.overwrite, "overwrite", false, "")
+	fs.BoolVar(&cd.merge, "m", false, "")
+	fs.BoolVar(&cd.merge, "merge", false, "")
+
+	args, err := directiveArgs(s)
+	if err != nil {
+		return cd, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return cd, err
+	}
+	if fs.NArg() > 0 {
+		return cd, errors.New("unexpected argument " + fs.Arg(0))
+	}
+	return cd, nil
+}
+
+// directiveArgs splits the text of a comment directive into the arguments after the generate tag, keeping quoted
+// arguments together and removing their quotes
+func directiveArgs(s string) ([]string, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(s, "//"))
+	// the first word is the generate tag itself
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i == -1 {
+		return nil, nil
+	}
+	var args []string
+	var arg []rune
+	var quote rune
+	inArg := false
+	for _, r := range s[i:] {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg = append(arg, r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, string(arg))
+				arg, inArg = nil, false
+			}
+		default:
+			arg, inArg = append(arg, r), true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return args, nil
+}
+
+// Apply returns a copy of the Tagger with the options given in the directive, leaving t unchanged
+func (c *CommentDirective) Apply(t *Tagger) (*Tagger, error) {
+	o, f := *t.Options, t.Filters
+	if n := countTrue(c.appendMode, c.overwrite, c.merge); n > 1 {
+		return nil, errors.New("only one of -a, -o and -m may be given")
+	}
+	var err error
+	c.FlagSet.Visit(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		var ok bool
+		switch fl.Name {
+		case "t", "tag-name":
+			if o.Tags, o.Cases, err = parseTags(c.tag); err == nil {
+				o.Tag = o.Tags[0]
+			}
+		case "case":
+			if !IsValidCase(c.caseName) {
+				err = sterrors.ErrUnknownCase(c.caseName)
+			}
+			o.Case = c.caseName
+		case "i", "ignored-fields":
+			f.IgnoredFields = splitList(c.ignoredFields)
+		case "initialisms":
+			o.Initialisms = splitList(c.initialisms)
+		case "embedded":
+			if o.Embedded, ok = EmbeddedPolicies[c.embedded]; !ok {
+				err = sterrors.ErrUnknownEmbeddedPolicy(c.embedded)
+			}
+		case "multi":
+			if o.MultiNames, ok = MultiNamePolicies[c.multi]; !ok {
+				err = sterrors.ErrUnknownMultiNamePolicy(c.multi)
+			}
+		case "opts":
+			o.OptionRules, err = parseOptionRules(c.opts)
+		case "template":
+			o.Templates, err = parseTemplates(c.templates)
+		case "a", "append":
+			if c.appendMode {
+				o.AppendMode = Append
+			}
+		case "o", "overwrite":
+			if c.overwrite {
+				o.AppendMode = Overwrite
+			}
+		case "m", "merge":
+			if c.merge {
+				o.AppendMode = Update
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	directed := *t
+	directed.Options, directed.Filters = &o, f
+	return &directed, nil
+}
+
+// countTrue returns the number of values that are true
+func countTrue(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// structTagger returns the Tagger to use for the struct at the top of the stack, which is the inspection's Tagger with
+// the comment directives above the type declaration that the struct is part of applied in order
+func (in *inspection) structTagger() (*Tagger, error) {
+	var docs []*ast.CommentGroup
+	for i := len(in.stack) - 1; i >= 0; i-- {
+		spec, ok := in.stack[i].(*ast.TypeSpec)
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			if decl, ok := in.stack[i-1].(*ast.GenDecl); ok {
+				docs = append(docs, decl.Doc)
+			}
+		}
+		docs = append(docs, spec.Doc)
+		break
+	}
+
+	t := in.Tagger
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, c := range doc.List {
+			if !IsCommentDirective(c.Text, in.Options.GenerateTag) {
+				continue
+			}
+			cd, err := NewCommentDirective(c.Text)
+			if err == nil {
+				t, err = cd.Apply(t)
+			}
+			if err != nil {
+				return nil, sterrors.ErrInvalidDirective(in.position(c.Pos()), c.Text, err.Error())
+			}
+		}
+	}
+	return t, nil
+}
+
+// FieldDirectivePrefix is the prefix of the directives that can be given in the doc or line comment of a field, as in
+// // st:skip
+const FieldDirectivePrefix = "st:"
+
+// FieldDirective holds the directives given in the comments of a field. Skip leaves the field alone, Name is written
+// as the name of the field for every tag key, and Options are added to every tag key that is written.
+type FieldDirective struct {
+	Skip    bool
+	Name    string
+	Options []string
+}
+
+// ParseFieldDirectives parses every directive in the doc and line comments of the field f, which may be nil. Each
+// directive is a word starting with FieldDirectivePrefix: st:skip, st:name=<name>, or st: followed by the option of one
+// of the DefaultOptionRules, such as st:omitempty. Only comments whose text starts with a directive hold directives,
+// and they may hold more than one, so st: in the middle of any other comment is left alone. The comment that holds a
+// malformed directive is returned along with the error.
+func ParseFieldDirectives(f *ast.Field) (FieldDirective, *ast.Comment, error) {
+	var d FieldDirective
+	if f == nil {
+		return d, nil, nil
+	}
+	for _, group := range []*ast.CommentGroup{f.Doc, f.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if !strings.HasPrefix(text, FieldDirectivePrefix) {
+				continue
+			}
+			for _, word := range strings.Fields(text) {
+				if !strings.HasPrefix(word, FieldDirectivePrefix) {
+					continue
+				}
+				if err := d.parse(strings.TrimPrefix(word, FieldDirectivePrefix)); err != nil {
+					return d, c, err
+				}
+			}
+		}
+	}
+	return d, nil, nil
+}
+
+// parse adds a single directive, without its prefix, to the FieldDirective
+func (d *FieldDirective) parse(directive string) error {
+	switch {
+	case directive == "skip":
+		d.Skip = true
+	case strings.HasPrefix(directive, "name="):
+		d.Name = strings.TrimPrefix(directive, "name=")
+		if d.Name == "" || strings.ContainsAny(d.Name, "`\",") {
+			return errors.New("st:name must be given a name without quotes, backticks or commas")
+		}
+	default:
+		for _, r := range DefaultOptionRules {
+			if r.Option == directive {
+				if !contains(d.Options, directive) {
+					d.Options = append(d.Options, directive)
+				}
+				return nil
+			}
+		}
+		return errors.New("unknown field directive " + FieldDirectivePrefix + directive)
+	}
+	return nil
+}
+
+// checkFieldDirectives returns an error for the first field of the struct that has a malformed directive, or an st:name
+// directive and more than one name, since the names cannot share one name in their tags
+func (in *inspection) checkFieldDirectives(s *ast.StructType) error {
+	for _, f := range s.Fields.List {
+		d, c, err := ParseFieldDirectives(f)
+		if err != nil {
+			return sterrors.ErrInvalidDirective(in.position(c.Pos()), c.Text, err.Error())
+		}
+		if d.Name != "" && len(f.Names) > 1 {
+			return sterrors.ErrInvalidDirective(in.position(f.Pos()), FieldDirectivePrefix+"name="+d.Name,
+				"a field with more than one name cannot give them all one name")
+		}
+	}
+	return nil
+}
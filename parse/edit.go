@@ -0,0 +1,105 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alistanis/st/sterrors"
+)
+
+// Edit is a single change to a source file. The bytes from Pos to End, which are byte offsets into the source, are
+// replaced with New. Old holds the bytes being replaced, and is empty when New is inserted at Pos.
+type Edit struct {
+	Pos int
+	End int
+	Old string
+	New string
+}
+
+// String returns the edit in the format offset:end: "old" -> "new"
+func (e Edit) String() string {
+	return fmt.Sprintf("%d:%d: %q -> %q", e.Pos, e.End, e.Old, e.New)
+}
+
+// ApplyEdits applies a list of edits to src and returns the result in a new []byte; src is not modified. The edits
+// may be given in any order, but they may not overlap, and the Old text of each edit must match src.
+func ApplyEdits(src []byte, edits []Edit) ([]byte, error) {
+	sorted := SortEdits(edits)
+	var buf bytes.Buffer
+	last := 0
+	for i, e := range sorted {
+		if e.Pos < 0 || e.End < e.Pos || e.End > len(src) {
+			return nil, sterrors.ErrInvalidEdit(e.String(), len(src))
+		}
+		if e.Pos < last {
+			return nil, sterrors.ErrOverlappingEdits(sorted[i-1].String(), e.String())
+		}
+		if old := string(src[e.Pos:e.End]); old != e.Old {
+			return nil, sterrors.ErrEditMismatch(e.String(), old)
+		}
+		buf.Write(src[last:e.Pos])
+		buf.WriteString(e.New)
+		last = e.End
+	}
+	buf.Write(src[last:])
+	return buf.Bytes(), nil
+}
+
+// SortEdits returns a copy of edits sorted by position
+func SortEdits(edits []Edit) []Edit {
+	sorted := append([]Edit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Pos < sorted[j].Pos
+	})
+	return sorted
+}
+
+// offset returns the byte offset of pos in the source of f
+func offset(f *ast.File, pos token.Pos) int {
+	return int(pos - f.FileStart)
+}
+
+// lineCol returns the line and column (both starting at 1) of the byte offset off in src
+func lineCol(src []byte, off int) (int, int) {
+	line := bytes.Count(src[:off], []byte("\n")) + 1
+	col := off - bytes.LastIndexByte(src[:off], '\n')
+	return line, col
+}
+
+// tagLiteral returns the Go literal for the contents of a tag, which is a raw string literal unless the tag holds a
+// backtick (as a template result may), in which case it is an interpreted string literal
+func tagLiteral(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// replaceTagEdit returns an edit that replaces the tag literal of field with a tag holding newTag
+func replaceTagEdit(f *ast.File, field *ast.Field, newTag string) Edit {
+	return Edit{
+		Pos: offset(f, field.Tag.Pos()),
+		End: offset(f, field.Tag.End()),
+		Old: field.Tag.Value,
+		New: tagLiteral(newTag)}
+}
+
+// addTagEdit returns an edit that adds a tag holding newTag to a field that has none
+func addTagEdit(f *ast.File, field *ast.Field, newTag string) Edit {
+	end := offset(f, field.End())
+	return Edit{Pos: end, End: end, New: " " + tagLiteral(newTag)}
+}
+
+// removeTagEdit returns an edit that removes the tag literal of field. The space before the literal is left for gofmt to
+// remove.
+func removeTagEdit(f *ast.File, field *ast.Field) Edit {
+	return Edit{
+		Pos: offset(f, field.Tag.Pos()),
+		End: offset(f, field.Tag.End()),
+		Old: field.Tag.Value}
+}
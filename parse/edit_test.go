@@ -0,0 +1,88 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alistanis/st/sterrors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEdits(t *testing.T) {
+	Convey("Given a struct with a tagged field and an untagged field", t, func() {
+		src := []byte(strings.Replace(`package test
+
+type TestStruct struct {
+	Tagged   string %sjson:"Tagged"%s
+	Untagged string
+}
+`, "%s", "`", -1))
+		f, _, err := Parse(src, "test.go")
+		So(err, ShouldBeNil)
+		opts := DefaultOptions()
+		opts.AppendMode = Overwrite
+		tagger := NewTagger(opts, Filters{})
+
+		Convey("We get one edit per field, sorted by position", func() {
+			edits, err := tagger.Edits(f, src)
+			So(err, ShouldBeNil)
+			So(edits, ShouldResemble, []Edit{
+				{Pos: 56, End: 71, Old: "`json:\"Tagged\"`", New: "`json:\"tagged\"`"},
+				{Pos: 88, End: 88, New: " `json:\"untagged\"`"},
+			})
+			So(string(src[edits[0].Pos:edits[0].End]), ShouldEqual, edits[0].Old)
+		})
+
+		Convey("We can apply the edits without modifying the source", func() {
+			original := string(src)
+			edits, err := tagger.Edits(f, src)
+			So(err, ShouldBeNil)
+			data, err := ApplyEdits(src, edits)
+			So(err, ShouldBeNil)
+			So(string(src), ShouldEqual, original)
+			So(string(data), ShouldContainSubstring, "Untagged string `json:\"untagged\"`")
+		})
+
+		Convey("Overlapping edits return an error", func() {
+			_, err := ApplyEdits(src, []Edit{{Pos: 1, End: 5, Old: "acka"}, {Pos: 3, End: 4, Old: "k"}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrOverlappingEdits(`1:5: "acka" -> ""`, `3:4: "k" -> ""`).Error())
+		})
+
+		Convey("Edits that do not match the source return an error", func() {
+			_, err := ApplyEdits(src, []Edit{{Pos: 0, End: 7, Old: "package", New: "pkg"}, {Pos: 8, End: 12, Old: "nope"}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrEditMismatch(`8:12: "nope" -> ""`, "test").Error())
+		})
+
+		Convey("Edits that are out of range return an error", func() {
+			_, err := ApplyEdits(src, []Edit{{Pos: len(src), End: len(src) + 1}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given nested structs and comments with multi-byte characters", t, func() {
+		src := []byte(`package test
+
+// Ünïcödé 😀
+type Outer struct {
+	Inner struct {
+		Name string // nämé
+	} // ïnnér
+	Count int /* çount */
+}
+`)
+		data, err := NewTagger(nil, Filters{}).ProcessBytes(src, "test.go")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, strings.Replace(`package test
+
+// Ünïcödé 😀
+type Outer struct {
+	Inner struct {
+		Name string %sjson:"name"%s // nämé
+	} %sjson:"inner"%s // ïnnér
+	Count int %sjson:"count"%s /* çount */
+}
+`, "%s", "`", -1))
+	})
+}
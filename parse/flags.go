@@ -8,49 +8,124 @@ import (
 	"github.com/alistanis/st/sterrors"
 )
 
+// UntagCommand is the command given as the first argument to remove tags instead of adding them, as in st untag -t=xml ./...
+const UntagCommand = "untag"
+
 var (
-	// Case determines the case to use when tagging structs - either Camel or Snake
+	// Operation is the operation that ST will perform, set to RemoveTags when the untag command is given
+	Operation = AddTags
+	// Case determines the case to use when tagging structs - one of SupportedCases
 	Case = DefaultCase
-	// Tag determines the tag to use when tagging structs - default is json
+	// Tag determines the tag to use when tagging structs - default is json. May be a comma separated list of tags.
 	Tag = DefaultTag
+	// Tags is the list of tags parsed from Tag
+	Tags = []string{DefaultTag}
+	// TagCases maps tags to the case given for them in Tag, for example -t=json=camel,db=snake
+	TagCases = make(map[string]string)
+	// InitialismsString is a comma separated list of initialisms to keep together as one word provided as a command line flag
+	InitialismsString string
+	// Initialisms is the list of initialisms parsed from InitialismsString
+	Initialisms []string
 	// FlagAppend is true if -a or -append are provided as command line flags - appends to tags instead of overwriting or skipping entirely
 	FlagAppend bool
 	// FlagOverwrite is true if -o or -overwrite are provided as command line flags - overwrites existing tags
 	FlagOverwrite bool
-	c             bool
-	s             bool
+	// FlagMerge is true if -m or -merge are provided as command line flags - replaces only the name in existing tags
+	FlagMerge bool
 	// Verbose sets the default for how much information is printed to standard out
 	Verbose bool
 	// Write is true if -w or -write are provided as command line flags - this will write to the original source file
 	Write bool
+	// ContinueOnError is true if -k or -keep-going are provided as command line flags - files that fail are reported at the end instead of stopping st
+	ContinueOnError bool
+	// Check is true if -l or -check are provided as command line flags - files that would change are listed and nothing is written
+	Check bool
+	// FlagDiff is true if -d or -diff are provided as command line flags - a diff is printed instead of the whole file
+	FlagDiff bool
+	// Color is true if -color is provided as a command line flag - diffs are colored
+	Color bool
+	// IncludeTests is true if -include-tests is provided as a command line flag - _test.go files found in directories will be tagged
+	IncludeTests bool
+	// Filename is the name used for source read from stdin, provided as a command line flag
+	Filename string
+	// Types is true if -types is provided as a command line flag - the package of each file will be type checked
+	Types bool
 	// IgnoredFieldsString is a comma separated list of ignored fields provided as a command line flag
 	IgnoredFieldsString string
 	// IgnoredStructsString is a comma separated list of ignored structs provided as a command line flag
 	IgnoredStructsString string
+	// IncludedStructsString is a comma separated list of the only structs to tag provided as a command line flag
+	IncludedStructsString string
+	// SkippedKeypairsString is a comma separated list of Struct.Field keypairs to skip provided as a command line flag
+	SkippedKeypairsString string
+	// IncludedKeypairsString is a comma separated list of the only Struct.Field keypairs to tag provided as a command line flag
+	IncludedKeypairsString string
+	// EmbeddedString is the name of the embedded field policy provided as a command line flag
+	EmbeddedString string
+	// Embedded is the embedded field policy parsed from EmbeddedString
+	Embedded = EmbeddedSkip
+	// MultiNamesString is the name of the multi-name field policy provided as a command line flag
+	MultiNamesString string
+	// MultiNames is the multi-name field policy parsed from MultiNamesString
+	MultiNames = MultiReport
+	// OptionRulesString is a comma separated list of the names of option rules to apply provided as a command line flag
+	OptionRulesString string
+	// OptionRules is the list of option rules parsed from OptionRulesString
+	OptionRules []OptionRule
+	// ConfigPath is the path of the config file to use provided as a command line flag. If it is not given, ConfigFileName
+	// is looked for in the directory being tagged and every directory above it.
+	ConfigPath string
+	// PresetString is a comma separated list of the names of presets to use provided as a command line flag
+	PresetString string
+	// TemplateStrings is the list of key:"template" tag templates provided as repeated command line flags
+	TemplateStrings stringList
+	// Templates is the list of tag templates parsed from TemplateStrings
+	Templates []TagTemplate
+	// RenameKeysString is a comma separated list of old:new tag keys to rename provided as a command line flag
+	RenameKeysString string
+	// DeriveKeysString is a comma separated list of new=source tag keys to derive provided as a command line flag
+	DeriveKeysString string
+	// RenameKeys is the list of tag keys to rename parsed from RenameKeysString
+	RenameKeys []KeyMapping
+	// DeriveKeys is the list of tag keys to derive parsed from DeriveKeysString
+	DeriveKeys []KeyMapping
 	// AppendMode is the mode that ST will operate in. Default is to skip existing tags, can be set to Append or Overwrite
 	AppendMode = SkipExisting
 	// TagMode is the mode that ST operates on when tagging. Default is to tag all structs/fields.
 	TagMode = TagAll
+	// Configs resolves the settings of config files and presets for each directory that is tagged
+	Configs *ConfigSet
 	// GoFile is the name of the GoFile as given by go generate to os.Environ ($GOFILE)
 	GoFile string
 )
 
 // stringVars sets up all string command line variable bindings
 func stringVars() {
-	flag.StringVar(&Tag, "t", "json", "The struct tag to use when tagging. Example: -t=json ")
-	flag.StringVar(&Tag, "tag-name", "json", "The struct tag to use when tagging. Example: --tag-name=json ")
+	flag.StringVar(&Case, "case", DefaultCase, "The case to use for tag names. One of snake, camel, kebab, pascal, screaming (SCREAMING_SNAKE), lower, dot or keep (the field name as it is).")
+	flag.StringVar(&Tag, "t", "json", "The struct tag to use when tagging. Multiple tags may be given as a comma separated list, each with an optional case. Example: -t=json=camel,db ")
+	flag.StringVar(&Tag, "tag-name", "json", "The struct tag to use when tagging. Multiple tags may be given as a comma separated list, each with an optional case. Example: --tag-name=json=camel,db ")
+	flag.StringVar(&InitialismsString, "initialisms", "", "A comma separated list of initialisms to keep together as one word when formatting names, along with common initialisms such as ID, URL and HTTP. Example: -initialisms=GRPC,K8S")
 	flag.StringVar(&IgnoredFieldsString, "i", "", "A comma separated list of fields to ignore. Will use the format json:\"-\".")
 	flag.StringVar(&IgnoredFieldsString, "ignored-fields", "", "A comma separated list of fields to ignore. Will use the format json:\"-\".")
 	flag.StringVar(&IgnoredStructsString, "is", "", "A comma separated list of structs to ignore. Will not tag any fields in the struct.")
 	flag.StringVar(&IgnoredStructsString, "ignored-structs", "", "A comma separated list of structs to ignore. Will not tag any fields in the struct.")
+	flag.StringVar(&IncludedStructsString, "only-structs", "", "A comma separated list of structs to tag. No other structs will be tagged.")
+	flag.StringVar(&SkippedKeypairsString, "skip", "", "A comma separated list of Struct.Field keypairs to skip. Will not tag the field. Example: -skip=User.Password")
+	flag.StringVar(&IncludedKeypairsString, "only", "", "A comma separated list of Struct.Field keypairs to tag. No other fields will be tagged. Example: -only=User.Name,User.Email")
+	flag.StringVar(&EmbeddedString, "embedded", "skip", "How to tag embedded fields: skip them, tag them with the name of their type, or inline them with yaml:\",inline\", bson:\",inline\" and mapstructure:\",squash\" (other tags are left off). One of skip, name or inline.")
+	flag.StringVar(&MultiNamesString, "multi", "report", "How to tag fields with more than one name, such as X, Y int: report them as warnings without tagging them, or split them into one field for each name. One of report or split.")
+	flag.StringVar(&OptionRulesString, "opts", "", "A comma separated list of option rules to apply when tagging: omitempty (pointers, slices, maps and interfaces), string (int64 and uint64, json only) and inline (embedded fields, yaml and bson only, which are tagged with only ,inline even when -embedded=skip). A rule may be given for one tag key as key:rule. Example: -opts=omitempty,string or -opts=bson:omitempty")
+	flag.StringVar(&Filename, "filename", "", "The name of the file that source read from stdin (given as the path -) comes from, used in errors and to find the config file and the rest of the package for -types. Example: st -filename=models/user.go - < models/user.go")
+	flag.StringVar(&ConfigPath, "config", "", "The config file to use. By default "+ConfigFileName+" is looked for in the directory being tagged and every directory above it. Flags that are given take precedence over the config file.")
+	flag.StringVar(&PresetString, "preset", "", "A comma separated list of presets to use, which set the tag keys, cases, options and embedded field policy for a library. Flags that are given and settings in the config file take precedence over presets. One or more of "+strings.Join(PresetNames(), ", ")+". Example: -preset=json,gorm")
+	TemplateStrings = nil
+	flag.Var(&TemplateStrings, "template", "A tag written with a text/template, as key:\"template\". May be given more than once. The template can use .Name, the name in every case (.Snake, .Camel, .Kebab, .Pascal, .Screaming, .Lower, .Dot), .Struct, .Type, .IsPointer, .IsSlice, .IsMap and .Doc. Example: -template='gorm:\"column:{{.Snake}}\"'")
+	flag.StringVar(&RenameKeysString, "rename-key", "", "A comma separated list of old:new tag keys to rename in existing tags, keeping their values. Nothing is tagged. Example: -rename-key=db:sql")
+	flag.StringVar(&DeriveKeysString, "derive", "", "A comma separated list of new=source tag keys to add to existing tags, copying the value of source. Nothing is tagged. Example: -derive=yaml=json")
 }
 
 // boolVars sets up all boolean command line variable bindings
 func boolVars() {
-	flag.BoolVar(&c, "c", false, "Sets the struct tag to camel case.")
-	flag.BoolVar(&c, "camel", false, "Sets the struct tag to camel case")
-	flag.BoolVar(&s, "s", false, "Sets the struct tag to snake case.")
-	flag.BoolVar(&s, "snake", false, "Sets the struct tag to snake case.")
 	flag.BoolVar(&FlagAppend, "a", false, "Sets mode to append mode. Will append to existing tags. Default behavior skips existing tags.")
 	flag.BoolVar(&FlagAppend, "append", false, "Sets mode to append mode. Will append to existing tags. Default behavior skips existing tags.")
 	flag.BoolVar(&Verbose, "v", false, "Sets mode to verbose.")
@@ -59,10 +134,22 @@ func boolVars() {
 	flag.BoolVar(&Write, "write", false, "Sets mode to write to source file. The default is a dry run that prints the results to stdout.")
 	flag.BoolVar(&FlagOverwrite, "o", false, "Sets mode to overwrite mode. Will overwrite existing tags (completely). Default behavior skips existing tags.")
 	flag.BoolVar(&FlagOverwrite, "overwrite", false, "Sets mode to overwrite mode. Will overwrite existing tags (completely). Default behavior skips existing tags.")
+	flag.BoolVar(&FlagMerge, "m", false, "Sets mode to merge mode. Will replace only the name in existing tags, keeping options such as omitempty and all other tags. Default behavior skips existing tags.")
+	flag.BoolVar(&FlagMerge, "merge", false, "Sets mode to merge mode. Will replace only the name in existing tags, keeping options such as omitempty and all other tags. Default behavior skips existing tags.")
+	flag.BoolVar(&ContinueOnError, "k", false, "Keeps going when a file fails to parse or format, reporting every failure at the end. Failed files are never written.")
+	flag.BoolVar(&ContinueOnError, "keep-going", false, "Keeps going when a file fails to parse or format, reporting every failure at the end. Failed files are never written.")
+	flag.BoolVar(&Check, "l", false, "Lists the files that are not tagged and exits with a non-zero status if there are any. Nothing is written.")
+	flag.BoolVar(&Check, "check", false, "Lists the files that are not tagged and exits with a non-zero status if there are any. Nothing is written.")
+	flag.BoolVar(&FlagDiff, "d", false, "Prints a unified diff for each file instead of the whole file.")
+	flag.BoolVar(&FlagDiff, "diff", false, "Prints a unified diff for each file instead of the whole file.")
+	flag.BoolVar(&Color, "color", false, "Colors diffs printed with -d.")
+	flag.BoolVar(&Types, "types", false, "Type checks the package of each file, importing packages from their source, so that option rules and templates can use the resolved types of fields, such as named map types and time.Time.")
+	flag.BoolVar(&IncludeTests, "include-tests", false, "Includes _test.go files when tagging directories or patterns such as ./...")
 }
 
 // SetVars sets up all command line variable bindings
 func SetVars() {
+	Configs = nil
 	stringVars()
 	boolVars()
 	GoFile = os.Getenv("GOFILE")
@@ -71,37 +158,68 @@ func SetVars() {
 // Flags sets up command line bindings, calls flagParse(), and calls verify() to check command line flags
 func Flags() error {
 	SetVars()
+	parseCommand()
 	flag.Parse()
 	return verify()
 }
 
+// parseCommand sets Operation from the command given as the first argument, if there is one, and removes it from os.Args
+// so that the flags after it are parsed
+func parseCommand() {
+	Operation = AddTags
+	if len(os.Args) > 1 && os.Args[1] == UntagCommand {
+		Operation = RemoveTags
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+}
+
 func verify() error {
 
 	// If GoFile is set, we know that we're being run by go generate, so we append the file name as our last argument
-	// and we cheat so that we get the desired behavior
+	// and we cheat so that we get the desired behavior. The arguments are parsed again by the same FlagSet, after --
+	// so that none of them are taken for flags, which keeps the flags that were given for the config file.
 	if GoFile != "" {
-		SetArgs(append(flag.Args(), GoFile))
-		flag.Parse()
+		args := append([]string{"--"}, flag.Args()...)
+		if err := flag.CommandLine.Parse(append(args, GoFile)); err != nil {
+			return err
+		}
 	}
 
 	if flag.NArg() < 1 {
 		return sterrors.ErrNoPathsGiven
 	}
 
-	if c && s {
-		return sterrors.ErrMutuallyExclusiveParameters("c", "s")
+	if contains(flag.Args(), StdinPath) {
+		if flag.NArg() > 1 {
+			return sterrors.ErrStdinWithPaths
+		}
+		if Write {
+			return sterrors.ErrMutuallyExclusiveParameters("-", "w")
+		}
+	}
+
+	if err := configure(); err != nil {
+		return err
 	}
 
 	if FlagOverwrite && FlagAppend {
 		return sterrors.ErrMutuallyExclusiveParameters("o", "a")
 	}
 
-	if c {
-		Case = Camel
+	if FlagMerge && FlagOverwrite {
+		return sterrors.ErrMutuallyExclusiveParameters("m", "o")
+	}
+
+	if FlagMerge && FlagAppend {
+		return sterrors.ErrMutuallyExclusiveParameters("m", "a")
+	}
+
+	if Check && Write {
+		return sterrors.ErrMutuallyExclusiveParameters("check", "w")
 	}
 
-	if s {
-		Case = Snake
+	if !IsValidCase(Case) {
+		return sterrors.ErrUnknownCase(Case)
 	}
 
 	if FlagOverwrite {
@@ -112,8 +230,19 @@ func verify() error {
 		AppendMode = Append
 	}
 
+	if FlagMerge {
+		AppendMode = Update
+	}
+
 	sterrors.Verbose = Verbose
 
+	var err error
+	if Tags, TagCases, err = parseTags(Tag); err != nil {
+		return err
+	}
+
+	Initialisms = splitList(InitialismsString)
+
 	if IgnoredFieldsString != "" {
 		IgnoredFields = strings.Split(IgnoredFieldsString, ",")
 	}
@@ -121,6 +250,215 @@ func verify() error {
 	if IgnoredStructsString != "" {
 		IgnoredStructs = strings.Split(IgnoredStructsString, ",")
 	}
+
+	var ok bool
+	if Embedded, ok = EmbeddedPolicies[EmbeddedString]; !ok {
+		return sterrors.ErrUnknownEmbeddedPolicy(EmbeddedString)
+	}
+
+	if MultiNames, ok = MultiNamePolicies[MultiNamesString]; !ok {
+		return sterrors.ErrUnknownMultiNamePolicy(MultiNamesString)
+	}
+
+	if OptionRules, err = parseOptionRules(OptionRulesString); err != nil {
+		return err
+	}
+
+	if Templates, err = parseTemplates(TemplateStrings); err != nil {
+		return err
+	}
+
+	if err := verifyRenames(); err != nil {
+		return err
+	}
+	return verifyTagMode()
+}
+
+// parseTags splits a comma separated list of tag keys, each with an optional case as in json=camel, into the list of
+// keys and the cases given for them
+func parseTags(s string) ([]string, map[string]string, error) {
+	var tags []string
+	cases := make(map[string]string)
+	for _, t := range splitList(s) {
+		key, tagCase := t, ""
+		if i := strings.Index(t, "="); i != -1 {
+			key, tagCase = t[:i], t[i+1:]
+			if !IsValidCase(tagCase) {
+				return nil, nil, sterrors.ErrUnknownCase(tagCase)
+			}
+			cases[key] = tagCase
+		}
+		tags = append(tags, key)
+	}
+	if len(tags) == 0 {
+		return nil, nil, sterrors.ErrNoTagsGiven
+	}
+	return tags, cases, nil
+}
+
+// parseOptionRules returns the rules in DefaultOptionRules named in a comma separated list. A rule may be given for one
+// tag key as key:name, as in bson:omitempty, in which case it only adds its option to that key.
+func parseOptionRules(s string) ([]OptionRule, error) {
+	var rules []OptionRule
+	for _, v := range splitList(s) {
+		key, name := "", v
+		if i := strings.Index(v, ":"); i != -1 {
+			key, name = v[:i], v[i+1:]
+		}
+		r, ok := FindOptionRule(name)
+		if !ok || (key != "" && len(r.Keys) > 0 && !contains(r.Keys, key)) {
+			return nil, sterrors.ErrUnknownOptionRule(v)
+		}
+		if key != "" {
+			r.Keys = []string{key}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// parseTemplates parses every key:"template" tag template in list, and makes sure no tag key has more than one template
+func parseTemplates(list []string) ([]TagTemplate, error) {
+	var templates []TagTemplate
+	for _, s := range list {
+		tt, err := ParseTagTemplate(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range templates {
+			if other.Key == tt.Key {
+				return nil, sterrors.ErrInvalidTemplate(s, "more than one template for the key "+tt.Key)
+			}
+		}
+		templates = append(templates, tt)
+	}
+	return templates, nil
+}
+
+// verifyRenames sets RenameKeys and DeriveKeys from the command line flags, and sets Operation to RenameTags if any
+// were given
+func verifyRenames() error {
+	var err error
+	RenameKeys, err = splitKeyMappings(RenameKeysString, ":", false)
+	if err != nil {
+		return err
+	}
+	DeriveKeys, err = splitKeyMappings(DeriveKeysString, "=", true)
+	if err != nil {
+		return err
+	}
+	if len(RenameKeys) == 0 && len(DeriveKeys) == 0 {
+		return nil
+	}
+	if Operation == RemoveTags {
+		if len(RenameKeys) > 0 {
+			return sterrors.ErrMutuallyExclusiveParameters(UntagCommand, "rename-key")
+		}
+		return sterrors.ErrMutuallyExclusiveParameters(UntagCommand, "derive")
+	}
+	Operation = RenameTags
+	return nil
+}
+
+// splitKeyMappings splits a comma separated list of key mappings, each made of two different keys separated by sep. If
+// reverse is true, each mapping is given as To and then From, as in new=source.
+func splitKeyMappings(s, sep string, reverse bool) ([]KeyMapping, error) {
+	format := "old" + sep + "new"
+	if reverse {
+		format = "new" + sep + "source"
+	}
+	var mappings []KeyMapping
+	for _, v := range splitList(s) {
+		keys := strings.Split(v, sep)
+		if len(keys) != 2 || keys[0] == "" || keys[1] == "" || keys[0] == keys[1] {
+			return nil, sterrors.ErrInvalidKeyMapping(v, format)
+		}
+		m := KeyMapping{From: keys[0], To: keys[1]}
+		if reverse {
+			m = KeyMapping{From: keys[1], To: keys[0]}
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+// verifyTagMode sets TagMode and the inclusion/exclusion lists from the command line flags
+func verifyTagMode() error {
+	TagMode = TagAll
+	var set []string
+	if IncludedStructsString != "" {
+		set = append(set, "only-structs")
+	}
+	if SkippedKeypairsString != "" {
+		set = append(set, "skip")
+	}
+	if IncludedKeypairsString != "" {
+		set = append(set, "only")
+	}
+	if len(set) > 1 {
+		return sterrors.ErrMutuallyExclusiveParameters(set[0], set[1])
+	}
+
+	if IgnoredStructsString != "" {
+		TagMode = SkipSpecifiedStructs
+	}
+
+	if IncludedStructsString != "" {
+		TagMode = IncludeSpecifiedStructs
+		IncludedStructs = splitList(IncludedStructsString)
+	}
+
+	if SkippedKeypairsString != "" {
+		TagMode = SkipStructAndFieldKeypairs
+		SkippedKeypairs = splitList(SkippedKeypairsString)
+		if err := verifyKeypairs(SkippedKeypairs); err != nil {
+			return err
+		}
+	}
+
+	if IncludedKeypairsString != "" {
+		TagMode = IncludeStructAndFieldKeypairs
+		IncludedKeypairs = splitList(IncludedKeypairsString)
+		if err := verifyKeypairs(IncludedKeypairs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyKeypairs checks that every keypair is in the Struct.Field format
+func verifyKeypairs(keypairs []string) error {
+	for _, k := range keypairs {
+		if structName, _ := SplitKeypair(k); structName == "" {
+			return sterrors.ErrInvalidKeypair(k)
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, trimming whitespace and dropping empty entries
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// stringList is a flag.Value that collects every value given for a flag that can be repeated
+type stringList []string
+
+// String returns the values given so far as a comma separated list
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set adds a value to the list
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
 	return nil
 }
 
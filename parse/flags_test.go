@@ -1,7 +1,10 @@
 package parse
 
 import (
+	"flag"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -14,17 +17,40 @@ func TestFlags(t *testing.T) {
 		// We trick the flag parser into thinking there is an additional parameter, when really, there isn't. This allows
 		// us to bypass the check in ParseFlags() for a path, but this will be caught in parse or in main either way
 		Convey("We can set the mode to camel case", func() {
-			SetArgs([]string{"-c", ""})
+			SetArgs([]string{"-case=camel", ""})
 			err := Flags()
 			So(err, ShouldBeNil)
-			So(c, ShouldBeTrue)
+			So(Case, ShouldEqual, Camel)
 		})
 
 		Convey("We can set the mode to snake case", func() {
-			SetArgs([]string{"-s", ""})
+			SetArgs([]string{"-case=snake", ""})
 			err := Flags()
 			So(err, ShouldBeNil)
-			So(s, ShouldBeTrue)
+			So(Case, ShouldEqual, Snake)
+		})
+
+		Convey("We can set the mode to every supported case", func() {
+			for _, c := range SupportedCases {
+				SetArgs([]string{"-case=" + c, ""})
+				err := Flags()
+				So(err, ShouldBeNil)
+				So(Case, ShouldEqual, c)
+			}
+		})
+
+		Convey("Snake case is the default", func() {
+			SetArgs([]string{""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(Case, ShouldEqual, Snake)
+		})
+
+		Convey("An unknown case returns an error", func() {
+			SetArgs([]string{"-case=shouting", ""})
+			err := Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrUnknownCase("shouting").Error())
 		})
 
 		Convey("Append mode is skip existing by default", func() {
@@ -45,6 +71,182 @@ func TestFlags(t *testing.T) {
 			So(AppendMode, ShouldEqual, Append)
 		})
 
+		Convey("We can set multiple tags", func() {
+			SetArgs([]string{"-t", "json, yaml,db", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(reflect.DeepEqual(Tags, []string{"json", "yaml", "db"}), ShouldBeTrue)
+		})
+
+		Convey("We can set a case for each tag", func() {
+			SetArgs([]string{"-t", "json=camel,db=snake,yaml", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(reflect.DeepEqual(Tags, []string{"json", "db", "yaml"}), ShouldBeTrue)
+			So(reflect.DeepEqual(TagCases, map[string]string{"json": Camel, "db": Snake}), ShouldBeTrue)
+		})
+
+		Convey("A tag in camel case starts with a lower case word, and other tags use the default case", func() {
+			SetArgs([]string{"-t", "json=camel,db", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			o := &Options{Tags: Tags, Tag: Tags[0], Cases: TagCases, Case: Case}
+			data, err := NewTagger(o, Filters{}).ProcessBytes([]byte("package test\n\ntype User struct {\n\tUserID int\n}\n"), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "UserID int `json:\"userId\" db:\"user_id\"`")
+		})
+
+		Convey("We can set check mode", func() {
+			SetArgs([]string{"-l", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(Check, ShouldBeTrue)
+		})
+
+		Convey("We can remove tags with the untag command", func() {
+			SetArgs([]string{UntagCommand, "-t=xml,bson", "./..."})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(Operation, ShouldEqual, RemoveTags)
+			So(Tags, ShouldResemble, []string{"xml", "bson"})
+			So(flag.Args(), ShouldResemble, []string{"./..."})
+
+			SetArgs([]string{""})
+			err = Flags()
+			So(err, ShouldBeNil)
+			So(Operation, ShouldEqual, AddTags)
+		})
+
+		Convey("We can rename and derive tag keys", func() {
+			SetArgs([]string{"-rename-key=db:sql,mgo:bson", "-derive=yaml=json", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(Operation, ShouldEqual, RenameTags)
+			So(RenameKeys, ShouldResemble, []KeyMapping{{From: "db", To: "sql"}, {From: "mgo", To: "bson"}})
+			So(DeriveKeys, ShouldResemble, []KeyMapping{{From: "json", To: "yaml"}})
+		})
+
+		Convey("Invalid key mappings return an error", func() {
+			SetArgs([]string{"-rename-key=db", ""})
+			err := Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrInvalidKeyMapping("db", "old:new").Error())
+
+			SetArgs([]string{"-derive=yaml:json", ""})
+			err = Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrInvalidKeyMapping("yaml:json", "new=source").Error())
+		})
+
+		Convey("Renaming keys cannot be combined with untag", func() {
+			SetArgs([]string{UntagCommand, "-rename-key=db:sql", ""})
+			err := Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrMutuallyExclusiveParameters(UntagCommand, "rename-key").Error())
+		})
+
+		Convey("We can set the embedded field policy", func() {
+			SetArgs([]string{""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(Embedded, ShouldEqual, EmbeddedSkip)
+
+			SetArgs([]string{"-embedded=inline", ""})
+			err = Flags()
+			So(err, ShouldBeNil)
+			So(Embedded, ShouldEqual, EmbeddedInline)
+
+			SetArgs([]string{"-embedded=flatten", ""})
+			err = Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrUnknownEmbeddedPolicy("flatten").Error())
+		})
+
+		Convey("We can set the multi-name field policy", func() {
+			SetArgs([]string{""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(MultiNames, ShouldEqual, MultiReport)
+
+			SetArgs([]string{"-multi=split", ""})
+			err = Flags()
+			So(err, ShouldBeNil)
+			So(MultiNames, ShouldEqual, MultiSplit)
+
+			SetArgs([]string{"-multi=join", ""})
+			err = Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrUnknownMultiNamePolicy("join").Error())
+		})
+
+		Convey("We can give more initialisms", func() {
+			SetArgs([]string{"-initialisms=GRPC, K8S", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(Initialisms, ShouldResemble, []string{"GRPC", "K8S"})
+		})
+
+		Convey("We can set option rules", func() {
+			SetArgs([]string{"-opts=omitempty,string", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(len(OptionRules), ShouldEqual, 2)
+			So(OptionRules[0].Name, ShouldEqual, "omitempty")
+			So(OptionRules[1].Name, ShouldEqual, "string")
+
+			SetArgs([]string{"-opts=required", ""})
+			err = Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrUnknownOptionRule("required").Error())
+
+			SetArgs([]string{"-opts=bson:omitempty,json:string", ""})
+			err = Flags()
+			So(err, ShouldBeNil)
+			So(OptionRules[0].Keys, ShouldResemble, []string{"bson"})
+			So(OptionRules[1].Keys, ShouldResemble, []string{JSON})
+
+			SetArgs([]string{"-opts=yaml:string", ""})
+			err = Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrUnknownOptionRule("yaml:string").Error())
+		})
+
+		Convey("We can give more than one template", func() {
+			SetArgs([]string{`-template=gorm:"column:{{.Snake}}"`, `-template=db:"{{.Lower}}"`, ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(len(Templates), ShouldEqual, 2)
+			So(Templates[0].Key, ShouldEqual, "gorm")
+			So(Templates[1].Key, ShouldEqual, "db")
+
+			SetArgs([]string{`-template=gorm:"column:{{.Snake}}"`, `-template=gorm:"{{.Lower}}"`, ""})
+			err = Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrInvalidTemplate(`gorm:"{{.Lower}}"`, "more than one template for the key gorm").Error())
+		})
+
+		Convey("We can read from stdin with a filename", func() {
+			SetArgs([]string{"-filename=models/user.go", "-"})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(Filename, ShouldEqual, "models/user.go")
+			So(flag.Args(), ShouldResemble, []string{StdinPath})
+		})
+
+		Convey("We can turn on type checking", func() {
+			SetArgs([]string{"-types", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(Types, ShouldBeTrue)
+		})
+
+		Convey("We can set append mode to merge", func() {
+			SetArgs([]string{"-merge", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(AppendMode, ShouldEqual, Update)
+		})
+
 		Convey("We can set ignored fields", func() {
 			SetArgs([]string{"-i", "ignore,this,field", ""})
 			err := Flags()
@@ -59,6 +261,36 @@ func TestFlags(t *testing.T) {
 			So(reflect.DeepEqual(IgnoredStructs, []string{"ignore", "these", "structs"}), ShouldBeTrue)
 		})
 
+		Convey("Tag mode is tag all by default", func() {
+			SetArgs([]string{""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(TagMode, ShouldEqual, TagAll)
+		})
+
+		Convey("We can set the structs to include", func() {
+			SetArgs([]string{"-only-structs", "User,Order", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(TagMode, ShouldEqual, IncludeSpecifiedStructs)
+			So(reflect.DeepEqual(IncludedStructs, []string{"User", "Order"}), ShouldBeTrue)
+		})
+
+		Convey("We can set the keypairs to skip", func() {
+			SetArgs([]string{"-skip", "User.Password", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(TagMode, ShouldEqual, SkipStructAndFieldKeypairs)
+			So(reflect.DeepEqual(SkippedKeypairs, []string{"User.Password"}), ShouldBeTrue)
+		})
+
+		Convey("We can set the keypairs to include", func() {
+			SetArgs([]string{"-only", "User.Name,User.Email", ""})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(TagMode, ShouldEqual, IncludeStructAndFieldKeypairs)
+			So(reflect.DeepEqual(IncludedKeypairs, []string{"User.Name", "User.Email"}), ShouldBeTrue)
+		})
 	})
 }
 
@@ -73,6 +305,22 @@ func TestGoGenerateDetection(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(GoFile, ShouldEqual, "test.go")
 			})
+
+			Convey("The flags that are given are kept for the config file", func() {
+				root, err := ioutil.TempDir(tempDir, "generate")
+				So(err, ShouldBeNil)
+				err = ioutil.WriteFile(filepath.Join(root, ConfigFileName), []byte(`{"case": "camel", "tags": ["db"]}`), 0664)
+				So(err, ShouldBeNil)
+				os.Setenv("GOFILE", filepath.Join(root, "user.go"))
+				SetArgs([]string{"-t=yaml"})
+				err = Flags()
+				So(err, ShouldBeNil)
+				So(flag.Args(), ShouldResemble, []string{filepath.Join(root, "user.go")})
+				So(Configs.Given["tag-name"], ShouldBeTrue)
+				values, _, err := Configs.Values(root)
+				So(err, ShouldBeNil)
+				So(values, ShouldResemble, map[string][]string{"case": {"camel"}})
+			})
 			Reset(func() {
 				os.Setenv("GOFILE", "")
 			})
@@ -90,12 +338,68 @@ func TestFlagErrors(t *testing.T) {
 			So(err, ShouldEqual, sterrors.ErrNoPathsGiven)
 		})
 
-		Convey("Given a set of mismatched case flags", func() {
+		Convey("We can return an error when the tag list is empty", func() {
+			SetArgs([]string{"-t", ",", ""})
+			err := Flags()
+			So(err, ShouldEqual, sterrors.ErrNoTagsGiven)
+		})
+
+		Convey("We can return an error when a tag is given an unknown case", func() {
+			SetArgs([]string{"-t", "json=shouting", ""})
+			err := Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrUnknownCase("shouting").Error())
+		})
+
+		Convey("Given a set of mismatched tag mode flags", func() {
 			Convey("A mutually exclusive parameters error is given", func() {
-				SetArgs([]string{"-c", "-s", ""})
+				SetArgs([]string{"-only-structs", "User", "-skip", "User.Password", ""})
+				err := Flags()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, sterrors.ErrMutuallyExclusiveParameters("only-structs", "skip").Error())
+			})
+		})
+
+		Convey("Given merge and overwrite flags", func() {
+			Convey("A mutually exclusive parameters error is given", func() {
+				SetArgs([]string{"-m", "-o", ""})
+				err := Flags()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, sterrors.ErrMutuallyExclusiveParameters("m", "o").Error())
+			})
+		})
+
+		Convey("Given stdin along with other paths", func() {
+			Convey("An error is given", func() {
+				SetArgs([]string{"-", "main.go"})
+				err := Flags()
+				So(err, ShouldEqual, sterrors.ErrStdinWithPaths)
+			})
+		})
+
+		Convey("Given stdin and the write flag", func() {
+			Convey("A mutually exclusive parameters error is given", func() {
+				SetArgs([]string{"-w", "-"})
+				err := Flags()
+				So(err.Error(), ShouldEqual, sterrors.ErrMutuallyExclusiveParameters("-", "w").Error())
+			})
+		})
+
+		Convey("Given check and write flags", func() {
+			Convey("A mutually exclusive parameters error is given", func() {
+				SetArgs([]string{"-check", "-w", ""})
+				err := Flags()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, sterrors.ErrMutuallyExclusiveParameters("check", "w").Error())
+			})
+		})
+
+		Convey("Given a malformed keypair", func() {
+			Convey("An invalid keypair error is given", func() {
+				SetArgs([]string{"-skip", "Password", ""})
 				err := Flags()
 				So(err, ShouldNotBeNil)
-				So(err.Error(), ShouldEqual, sterrors.ErrMutuallyExclusiveParameters("c", "s").Error())
+				So(err.Error(), ShouldEqual, sterrors.ErrInvalidKeypair("Password").Error())
 			})
 		})
 
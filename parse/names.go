@@ -0,0 +1,197 @@
+package parse
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/alistanis/st/sterrors"
+)
+
+// DefaultInitialisms contains the initialisms that are kept together as one word when splitting names, such as ID in
+// UserID. More can be given to SplitWords and FormatName, or with the -initialisms flag.
+var DefaultInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP", "HTTPS", "ID", "IP", "JSON", "LHS",
+	"QPS", "RAM", "RHS", "RPC", "SLA", "SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID", "UUID", "URI",
+	"URL", "UTF8", "VM", "XML", "XMPP", "XSRF", "XSS",
+	// UInt, as in UIntPointer
+	"UINT",
+}
+
+// FormatName formats the name in the case c, which is one of SupportedCases. Any initialisms given are kept together as
+// one word, along with DefaultInitialisms.
+func FormatName(n, c string, initialisms ...string) string {
+	if c == Keep {
+		return n
+	}
+	words := SplitWords(n, initialisms...)
+	switch c {
+	case Camel:
+		return joinWords(words, "", func(i int, w string) string {
+			if i == 0 {
+				return strings.ToLower(w)
+			}
+			return title(w)
+		})
+	case Pascal:
+		return joinWords(words, "", func(i int, w string) string { return title(w) })
+	case Snake:
+		return strings.ToLower(strings.Join(words, "_"))
+	case Screaming:
+		return strings.ToUpper(strings.Join(words, "_"))
+	case Kebab:
+		return strings.ToLower(strings.Join(words, "-"))
+	case Dot:
+		return strings.ToLower(strings.Join(words, "."))
+	case Lower:
+		return strings.ToLower(strings.Join(words, ""))
+	}
+	sterrors.Printf("Could not format string, Case is not set.\n")
+	return n
+}
+
+// joinWords joins the words with sep after formatting each one with format, which is given the index of the word
+func joinWords(words []string, sep string, format func(i int, w string) string) string {
+	for i, w := range words {
+		words[i] = format(i, w)
+	}
+	return strings.Join(words, sep)
+}
+
+// SplitWords splits a name into its words. Words are separated by anything other than a letter or a digit, and by
+// changes in case, so that UserName is split into User and Name. Digits stay with the word before them, so Int64 is one
+// word. A run of upper case letters is one word, except for its last letter when it is followed by a lower case letter,
+// so HTTPServer is split into HTTP and Server. Initialisms are always kept together, so UserIDs is split into User and
+// IDs, and runs of initialisms are split into each initialism, so IDAPI is split into ID and API. Any initialisms given
+// are used along with DefaultInitialisms.
+func SplitWords(s string, initialisms ...string) []string {
+	isInitialism := func(w string) bool {
+		w = strings.ToUpper(w)
+		return contains(DefaultInitialisms, w) || containsFold(initialisms, w)
+	}
+	var words []string
+	for _, chunk := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		words = append(words, splitChunk(chunk, isInitialism)...)
+	}
+	return joinInitialisms(words, isInitialism)
+}
+
+// splitChunk splits a chunk made only of letters and digits into words by changes in case
+func splitChunk(chunk string, isInitialism func(string) bool) []string {
+	runes := []rune(chunk)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, r := runes[i-1], runes[i]
+		switch {
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			words = append(words, string(runes[start:i]))
+			start = i
+		case unicode.IsLower(r) && unicode.IsUpper(prev) && i-start > 1:
+			// the last letter of an upper case run starts the next word, unless the run is an initialism that would be
+			// broken by it, as in IDs or IPv4. A plural s after a run of initialisms, as in UIDs or HTTPAPIs, is its
+			// own word, and joinInitialisms adds it to the last initialism.
+			run := string(runes[start:i])
+			plural := r == 's' && (i+1 == len(runes) || unicode.IsUpper(runes[i+1]))
+			if plural && (isInitialism(run) || splitInitialisms(run, isInitialism) != nil) {
+				words = append(words, run)
+				start = i
+			} else if isInitialism(run) && !isInitialism(string(runes[start:i-1])) {
+				words = append(words, run)
+				start = i
+			} else {
+				words = append(words, string(runes[start:i-1]))
+				start = i - 1
+			}
+		}
+	}
+	return append(words, string(runes[start:]))
+}
+
+// joinInitialisms joins words that make up an initialism (such as U and Int in UInt) back together, adds a plural s to
+// the initialism before it, and splits upper case words that are made up of several initialisms
+func joinInitialisms(words []string, isInitialism func(string) bool) []string {
+	var joined []string
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		for j := i + 3; j > i+1; j-- {
+			if j <= len(words) && isInitialism(strings.Join(words[i:j], "")) {
+				w = strings.Join(words[i:j], "")
+				i = j - 1
+				break
+			}
+		}
+		if w == "s" && len(joined) > 0 && isInitialism(joined[len(joined)-1]) {
+			joined[len(joined)-1] += w
+			continue
+		}
+		if parts := splitInitialisms(w, isInitialism); parts != nil {
+			joined = append(joined, parts...)
+			continue
+		}
+		joined = append(joined, w)
+	}
+	return joined
+}
+
+// splitInitialisms splits an upper case word that is not an initialism into the initialisms it is made of, taking the
+// longest initialism first. It returns nil if the word cannot be split completely.
+func splitInitialisms(w string, isInitialism func(string) bool) []string {
+	if w != strings.ToUpper(w) || isInitialism(w) {
+		return nil
+	}
+	var parts []string
+	for w != "" {
+		n := len(w)
+		for ; n > 0; n-- {
+			if isInitialism(w[:n]) {
+				break
+			}
+		}
+		if n == 0 {
+			return nil
+		}
+		parts = append(parts, w[:n])
+		w = w[n:]
+	}
+	return parts
+}
+
+// containsFold checks if s is in list, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// title returns the word with its first letter in upper case and the rest in lower case
+func title(w string) string {
+	runes := []rune(strings.ToLower(w))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
+// Underscore will change a string from a camelcased form to a string with underscores, keeping initialisms together.
+// Will change "::" to "/" to maintain compatibility with Rails's underscore
+func Underscore(str string) string {
+	parts := strings.Split(str, "::")
+	for i, p := range parts {
+		parts[i] = FormatName(p, Snake)
+	}
+	return strings.Join(parts, "/")
+}
+
+// CamelCase converts a string to the CamelCase version of it, keeping initialisms together. The first letter keeps its
+// case, so thisIsIt and ThisIsIt are both left alone.
+func CamelCase(src string) string {
+	return joinWords(SplitWords(src), "", func(i int, w string) string {
+		if i == 0 && !unicode.IsUpper([]rune(w)[0]) {
+			return strings.ToLower(w)
+		}
+		return title(w)
+	})
+}
@@ -0,0 +1,98 @@
+package parse
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSplitWords(t *testing.T) {
+	Convey("Given names with initialisms, digits and separators", t, func() {
+		cases := map[string][]string{
+			"UserName":    {"User", "Name"},
+			"UserID":      {"User", "ID"},
+			"UserIDs":     {"User", "IDs"},
+			"UIDs":        {"UIDs"},
+			"HTTPAPIs":    {"HTTP", "APIs"},
+			"TTLs":        {"TTLs"},
+			"UIDsByName":  {"UIDs", "By", "Name"},
+			"HTTPServer":  {"HTTP", "Server"},
+			"ServeHTTP":   {"Serve", "HTTP"},
+			"HTTPSProxy":  {"HTTPS", "Proxy"},
+			"IDAPI":       {"ID", "API"},
+			"APIKey":      {"API", "Key"},
+			"IPv4":        {"IP", "v4"},
+			"Int64":       {"Int64"},
+			"Int64Slice":  {"Int64", "Slice"},
+			"UTF8String":  {"UTF8", "String"},
+			"UIntPointer": {"UInt", "Pointer"},
+			"UIElement":   {"UI", "Element"},
+			"this_is-it":  {"this", "is", "it"},
+			"ABC":         {"ABC"},
+			"":            nil,
+		}
+
+		Convey("Each name is split into its words", func() {
+			for name, words := range cases {
+				So(SplitWords(name), ShouldResemble, words)
+			}
+		})
+
+		Convey("More initialisms can be given", func() {
+			So(SplitWords("GRPCServer"), ShouldResemble, []string{"GRPC", "Server"})
+			So(SplitWords("K8sCluster"), ShouldResemble, []string{"K8s", "Cluster"})
+			So(SplitWords("NewGRPCAPI", "grpc"), ShouldResemble, []string{"New", "GRPC", "API"})
+		})
+	})
+}
+
+func TestFormatName(t *testing.T) {
+	Convey("Names are formatted in snake case and camel case", t, func() {
+		cases := []struct {
+			name, snake, camel string
+		}{
+			{"UserID", "user_id", "userId"},
+			{"HTTPServer", "http_server", "httpServer"},
+			{"Int64", "int64", "int64"},
+			{"UIntPointer", "uint_pointer", "uintPointer"},
+			{"URLs", "urls", "urls"},
+			{"UIDs", "uids", "uids"},
+			{"HTTPAPIs", "http_apis", "httpApis"},
+			{"TTLs", "ttls", "ttls"},
+			{"ServerURL", "server_url", "serverUrl"},
+			{"ID", "id", "id"},
+		}
+		for _, c := range cases {
+			So(FormatName(c.name, Snake), ShouldEqual, c.snake)
+			So(FormatName(c.name, Camel), ShouldEqual, c.camel)
+		}
+		So(FormatName("GRPCServer", Snake, "GRPC"), ShouldEqual, "grpc_server")
+	})
+
+	Convey("Names are formatted in every supported case", t, func() {
+		expected := map[string]string{
+			Snake:     "http_server_id",
+			Camel:     "httpServerId",
+			Kebab:     "http-server-id",
+			Pascal:    "HttpServerId",
+			Screaming: "HTTP_SERVER_ID",
+			Lower:     "httpserverid",
+			Dot:       "http.server.id",
+			Keep:      "HTTPServerID",
+		}
+		So(len(expected), ShouldEqual, len(SupportedCases))
+		for _, c := range SupportedCases {
+			So(FormatName("HTTPServerID", c), ShouldEqual, expected[c])
+		}
+	})
+
+	Convey("Underscore keeps initialisms together", t, func() {
+		So(Underscore("UserID"), ShouldEqual, "user_id")
+		So(Underscore("Int64"), ShouldEqual, "int64")
+		So(Underscore("Admin::UserID"), ShouldEqual, "admin/user_id")
+	})
+
+	Convey("A name is left alone when the case is unknown", t, func() {
+		So(FormatName("UserID", "shouting"), ShouldEqual, "UserID")
+	})
+}
@@ -4,22 +4,19 @@ package parse
 
 import (
 	"bytes"
-	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
 	"io/ioutil"
 	"os"
-	"regexp"
+	"path/filepath"
+	"strconv"
 	"strings"
 
-	"reflect"
-
 	"go/format"
 
-	"path/filepath"
-
 	"github.com/alistanis/st/sterrors"
 )
 
@@ -31,19 +28,60 @@ const (
 	Overwrite
 	// SkipExisting skips existing tags whether or not they match tag or case
 	SkipExisting
+	// Update replaces only the name in an existing tag, keeping its options (such as omitempty) and every other tag
+	Update
+)
+
+// Operations
+const (
+	// AddTags adds the tag keys to fields, following the append mode
+	AddTags = iota
+	// RemoveTags removes the tag keys from fields, removing the tag altogether if no other keys are left
+	RemoveTags
+	// RenameTags renames keys in existing tags and derives new keys from existing ones, keeping their values
+	RenameTags
+)
+
+// Embedded field policies
+const (
+	// EmbeddedSkip skips embedded fields
+	EmbeddedSkip = iota
+	// EmbeddedName tags embedded fields with the name of their type, as if it were the field name
+	EmbeddedName
+	// EmbeddedInline tags embedded fields with the inline marker for each tag key in InlineMarkers, so that their fields
+	// are treated as fields of the outer struct. Keys without a marker, such as json (which promotes the fields of
+	// untagged embedded structs on its own), are left untagged.
+	EmbeddedInline
 )
 
+// Multi-name field policies, for fields such as X, Y int that share one tag
+const (
+	// MultiReport skips fields with more than one name and warns about each one that would have been tagged
+	MultiReport = iota
+	// MultiSplit splits fields with more than one name into one field for each name, each with its own tag
+	MultiSplit
+)
+
+// MultiNamePolicies maps the name of each multi-name field policy to its value
+var MultiNamePolicies = map[string]int{"report": MultiReport, "split": MultiSplit}
+
+// EmbeddedPolicies maps the name of each embedded field policy to its value
+var EmbeddedPolicies = map[string]int{"skip": EmbeddedSkip, "name": EmbeddedName, "inline": EmbeddedInline}
+
+// InlineMarkers maps tag keys to the option that inlines an embedded field for that key
+var InlineMarkers = map[string]string{"yaml": "inline", "bson": "inline", "mapstructure": "squash"}
+
 // Major Tag modes
 const (
 	// TagAll will tag all structs/fields (unless they are excluded in the IngoreStructs/IgnoreFields slices)
 	TagAll = iota
-	// SkipSpecifiedStructs (currently unimplemented)
+	// SkipSpecifiedStructs will tag all structs except those in the IgnoredStructs slice
 	SkipSpecifiedStructs
-	// IncludeSpecifiedStructs (currently unimplemented)
+	// IncludeSpecifiedStructs will only tag the structs in the IncludedStructs slice
 	IncludeSpecifiedStructs
-	// SkipStructAndFieldKeypairs (currently unimplemented)
+	// SkipStructAndFieldKeypairs will tag all fields except those given as Struct.Field in the SkippedKeypairs slice
 	SkipStructAndFieldKeypairs
-	// IncludeStructAndFieldKeypairs (currently unimplemented)
+	// IncludeStructAndFieldKeypairs will only tag the fields given as Struct.Field in the IncludedKeypairs slice
 	IncludeStructAndFieldKeypairs
 )
 
@@ -55,10 +93,39 @@ const (
 	Snake = "snake"
 	// Camel represents camel case
 	Camel = "camel"
+	// Kebab represents kebab case, as in user-id
+	Kebab = "kebab"
+	// Pascal represents pascal case, as in UserId
+	Pascal = "pascal"
+	// Screaming represents screaming snake case, as in USER_ID
+	Screaming = "screaming"
+	// Lower represents lower case, as in userid
+	Lower = "lower"
+	// Dot represents dot case, as in user.id
+	Dot = "dot"
+	// Keep keeps the field name as it is
+	Keep = "keep"
+	// StdinPath is the path given to read the source to tag from stdin and write the result to stdout
+	StdinPath = "-"
+	// StdinFilename is the name used for source read from stdin when no Filename is given
+	StdinFilename = "<standard input>"
 	// DefaultGenerateTag represents the default go generate tag that ST will respect
 	DefaultGenerateTag = "@st"
 )
 
+// SupportedCases contains every supported case
+var SupportedCases = []string{Snake, Camel, Kebab, Pascal, Screaming, Lower, Dot, Keep}
+
+// IsValidCase checks if c is one of the supported cases
+func IsValidCase(c string) bool {
+	for _, v := range SupportedCases {
+		if c == v {
+			return true
+		}
+	}
+	return false
+}
+
 // Defaults
 var (
 	// DefaultAppendMode is SkipExisting - will skip existing tags entirely
@@ -75,49 +142,17 @@ var (
 	IgnoredFields = make([]string, 0)
 	// IgnoredStructs contains strings for structs that are not to be tagged
 	IgnoredStructs = make([]string, 0)
-	// TODO - Add more sophisticated exclusion/inclusion after refactor
-
-)
-
-// localGlobals
-var (
-	lastCommentWithGenerateTag string
-	lastTypeName               string
+	// IncludedStructs contains strings for the only structs that are to be tagged when TagMode is IncludeSpecifiedStructs
+	IncludedStructs = make([]string, 0)
+	// SkippedKeypairs contains Struct.Field strings for fields that are not to be tagged when TagMode is SkipStructAndFieldKeypairs
+	SkippedKeypairs = make([]string, 0)
+	// IncludedKeypairs contains Struct.Field strings for the only fields that are to be tagged when TagMode is IncludeStructAndFieldKeypairs
+	IncludedKeypairs = make([]string, 0)
 )
 
-// CommentDirective represents a comment with //@st at its beginning.
-// I am really not a fan of treating comments as anything more than a comment, but Go unfortunately has no other constructs
-type CommentDirective struct {
-	BaseText string
-	FlagSet  *flag.FlagSet
-}
-
-// Args returns the underlying FlagSet.Args()
-func (c *CommentDirective) Args() []string {
-	return c.FlagSet.Args()
-}
-
-//   NewCommentDirective takes a string (which should be the text from an *ast.Comment), creates a new flag set using the comment
-//   as the flag set name with flag.ContinueOnError - flag.ExitOnError will call os.Exit() - and then parses the flags
-//   Comments: 1) I would pass in the *ast.Comment directly, but it is already initialized further up the call stack at this point
-//   	       2) There is some hackery of the flag package going on in here
-//   s should be in the following format:
-//   - It should only be one line
-//   - It should say //@st with no spaces
-//   - Commands given after //@st will be interpreted just like normal st commands
-//   - $GOFILE will be passed in as the final argument
-func NewCommentDirective(s string) (*CommentDirective, error) {
-	cd := &CommentDirective{BaseText: s, FlagSet: flag.NewFlagSet(s, flag.ContinueOnError)}
-	args := strings.Split(strings.TrimLeft(s, `//@st`), " ")
-	SetArgs(append(args, os.Getenv("GOFILE")))
-	err := cd.FlagSet.Parse(args)
-	return cd, err
-}
-
 // DefaultOptions returns a new *Options with all default values initialized
 func DefaultOptions() *Options {
 	return &Options{
-		//Tags:       []string{DefaultTag},
 		Tag:         DefaultTag,
 		Case:        DefaultCase,
 		AppendMode:  DefaultAppendMode,
@@ -127,56 +162,223 @@ func DefaultOptions() *Options {
 		GenerateTag: DefaultGenerateTag}
 }
 
-// SetOptions sets the current options to the options provided. (This is not thread safe if called from a goroutine)
+// SetOptions sets the current options to the options provided. (This is not thread safe if called from a goroutine, use
+// a Tagger instead)
 func SetOptions(o *Options) {
 	options = o
 }
 
-// Options represents package behavior options - will be expanded to take a list of tags to support go generate
+// Options represents package behavior options. Tags is the list of tag keys to write (or remove), in order - if it is
+// empty, Tag is used instead. Cases maps a tag key to the case used for that key, overriding Case. Operation is one of
+// AddTags, RemoveTags or RenameTags. RenameKeys and DeriveKeys are only used by RenameTags. OptionRules add options such
+// as omitempty to the tags that are written, depending on the type of each field. Embedded and MultiNames are the
+// embedded and multi-name field policies. Initialisms are kept together as one word when formatting names, along with
+// DefaultInitialisms. Templates write the value of their tag keys, which are written along with Tags. Types type checks
+// the package of each file so that option rules and templates can use the resolved types of fields. Filename is the
+// name used for source read from stdin. If Configs is not nil, the settings it gives for the directory of each file are
+// used for that file.
 type Options struct {
-	//Tags       []string
-	Tag         string
-	Case        string
-	AppendMode  int
-	TagMode     int
-	DryRun      bool
-	Verbose     bool
-	GenerateTag string
+	Operation       int
+	Embedded        int
+	MultiNames      int
+	RenameKeys      []KeyMapping
+	DeriveKeys      []KeyMapping
+	OptionRules     []OptionRule
+	Tags            []string
+	Tag             string
+	Case            string
+	Cases           map[string]string
+	Initialisms     []string
+	Templates       []TagTemplate
+	Types           bool
+	Filename        string
+	Configs         *ConfigSet
+	AppendMode      int
+	TagMode         int
+	DryRun          bool
+	Verbose         bool
+	GenerateTag     string
+	IncludeTests    bool
+	ContinueOnError bool
+	Check           bool
+	Diff            bool
+	Color           bool
 }
 
-// AndProcessFiles takes a list of paths, iterates over them, stats them, and then inspects source files
+// TagKeys returns the tag keys that will be written, falling back to Tag when Tags is empty
+func (o *Options) TagKeys() []string {
+	if len(o.Tags) > 0 {
+		return o.Tags
+	}
+	if o.Tag == "" {
+		return nil
+	}
+	return []string{o.Tag}
+}
+
+// Filters contains the lists of struct and field names that decide what a Tagger tags. Struct.Field keypairs are only
+// used when the Tagger's TagMode is SkipStructAndFieldKeypairs or IncludeStructAndFieldKeypairs.
+type Filters struct {
+	IgnoredFields    []string
+	IgnoredStructs   []string
+	IncludedStructs  []string
+	SkippedKeypairs  []string
+	IncludedKeypairs []string
+}
+
+// Tagger tags structs using its own Options and Filters. A Tagger keeps no state between calls, so it is safe to use
+// from multiple goroutines at once as long as its Options and Filters are not modified while it is in use.
+type Tagger struct {
+	Options *Options
+	Filters Filters
+	// types is the type information for the file being tagged, only set on the copy of the Tagger used for one file
+	types *typeInfo
+	// checker type checks the files of one run, only set on the copy of the Tagger used for the run
+	checker *typeChecker
+}
+
+// NewTagger returns a new *Tagger with the given options and filters. If o is nil, DefaultOptions() is used.
+func NewTagger(o *Options, f Filters) *Tagger {
+	if o == nil {
+		o = DefaultOptions()
+	}
+	return &Tagger{Options: o, Filters: f}
+}
+
+// defaultTagger returns a *Tagger using the package level options and filters
+func defaultTagger() *Tagger {
+	return NewTagger(options, Filters{
+		IgnoredFields:    IgnoredFields,
+		IgnoredStructs:   IgnoredStructs,
+		IncludedStructs:  IncludedStructs,
+		SkippedKeypairs:  SkippedKeypairs,
+		IncludedKeypairs: IncludedKeypairs})
+}
+
+// AndProcessFiles takes a list of paths, iterates over them, stats them, and then inspects source files using the package
+// level options and filters
 func AndProcessFiles(paths []string) error {
-	for _, p := range paths {
-		fi, err := os.Stat(p)
-		if err != nil {
+	return defaultTagger().AndProcessFiles(paths)
+}
+
+// AndProcessFiles takes a list of paths, expands any directories or patterns into .go files, and then inspects the source
+// files. See ExpandPaths. If the only path is StdinPath, the source is read from stdin instead.
+func (t *Tagger) AndProcessFiles(paths []string) error {
+	files := paths
+	if len(paths) != 1 || paths[0] != StdinPath {
+		var err error
+		if files, err = ExpandPaths(paths, t.Options.IncludeTests); err != nil {
 			return err
 		}
-		if fi.IsDir() {
-			return fmt.Errorf("Cannot use a directory as a path. Path: %s", fi.Name())
+	}
+	if t.Options.Types && t.checker == nil {
+		t = t.withTypeChecker(newTypeChecker())
+	}
+	errs := &sterrors.MultiError{Total: len(files)}
+	changed := 0
+	for _, p := range files {
+		c, err := t.processPath(p)
+		if err != nil {
+			if !t.Options.ContinueOnError {
+				return err
+			}
+			errs.Add(err)
 		}
-		data, err := ProcessFile(p)
-		if options.DryRun {
-			fmt.Println(string(data))
-		} else {
-			ioutil.WriteFile(p, data, 0664)
+		if c {
+			changed++
 		}
 	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return err
+	}
+	if changed > 0 {
+		return sterrors.ErrFilesNotTagged(changed)
+	}
 	return nil
 }
 
+// processPath processes a single file, printing the result on a dry run or writing it back to the file otherwise. A file
+// that fails to parse or format is never written. In diff mode a diff is printed in place of the result. In check mode
+// the name of the file is printed if tagging would change it, and nothing is written. processPath returns true if the
+// file was found to need changes in check mode. If path is StdinPath the source is read from stdin, named by the
+// Tagger's Filename, and the result is written to stdout exactly as it is.
+func (t *Tagger) processPath(path string) (bool, error) {
+	var src []byte
+	var err error
+	filename := path
+	if path == StdinPath {
+		filename = t.Options.Filename
+		if filename == "" {
+			filename = StdinFilename
+		}
+		src, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		src, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return false, err
+	}
+	if t.Options.Configs != nil {
+		if t, err = t.Options.Configs.Tagger(t, filepath.Dir(filename)); err != nil {
+			return false, err
+		}
+	}
+	data, err := t.ProcessBytes(src, filename)
+	if err != nil {
+		return false, err
+	}
+	if t.Options.Check {
+		if !WouldChange(src, data) {
+			return false, nil
+		}
+		fmt.Println(filename)
+		if t.Options.Diff {
+			os.Stdout.Write(Diff(filename, src, data, t.Options.Color))
+		}
+		return true, nil
+	}
+	switch {
+	case t.Options.Diff:
+		os.Stdout.Write(Diff(filename, src, data, t.Options.Color))
+	case path == StdinPath:
+		os.Stdout.Write(data)
+	case t.Options.DryRun:
+		fmt.Println(string(data))
+	}
+	if t.Options.DryRun || path == StdinPath {
+		return false, nil
+	}
+	return false, ioutil.WriteFile(path, data, 0664)
+}
+
+// WouldChange checks if the tagged output differs from the source for any reason other than formatting, by comparing it
+// with the gofmt'd source
+func WouldChange(src, tagged []byte) bool {
+	formatted, err := format.Source(src)
+	if err != nil {
+		formatted = src
+	}
+	return !bytes.Equal(formatted, tagged)
+}
+
 // File represents a basic file with a FileName(path) and the Data contained within the file
 type File struct {
 	FileName string
 	Data     []byte
 }
 
+// Process iterates over a []*File using the package level options and filters. See Tagger.Process.
+func Process(files []*File) ([]*File, error) {
+	return defaultTagger().Process(files)
+}
+
 // Process iterates over a []*File, processes the *Files, and returns the resulting []*File and the last error that occurred, if any
 // This function could potentially consume a lot of memory if an extraordinarily large set was passed to it
-func Process(files []*File) ([]*File, error) {
+func (t *Tagger) Process(files []*File) ([]*File, error) {
 	var lastErr error
 	var results []*File
 	for _, f := range files {
-		data, err := ProcessBytes(f.Data, f.FileName)
+		data, err := t.ProcessBytes(f.Data, f.FileName)
 		if err != nil {
 			lastErr = err
 			continue
@@ -187,13 +389,34 @@ func Process(files []*File) ([]*File, error) {
 	return results, lastErr
 }
 
-// ProcessBytes takes a []byte and filename, and inspects the data, returning that data in another []byte
+// ProcessBytes takes a []byte and filename, and inspects the data using the package level options and filters
 func ProcessBytes(data []byte, filename string) ([]byte, error) {
-	astFile, data, err := Parse(data, filename)
+	return defaultTagger().ProcessBytes(data, filename)
+}
+
+// ProcessBytes takes a []byte and filename, and inspects the data, returning that data in another []byte. If the
+// Tagger's Types option is set, the data is type checked along with the other files in the directory of filename that
+// are in the same package. Files tagged in one run by AndProcessFiles share the parsed package and its types.
+func (t *Tagger) ProcessBytes(data []byte, filename string) ([]byte, error) {
+	tagger := t
+	var astFile *ast.File
+	var err error
+	if t.Options.Types {
+		checker := t.checker
+		if checker == nil {
+			checker = newTypeChecker()
+		}
+		var ti *typeInfo
+		astFile, ti, err = checker.check(data, filename)
+		tagger = t.withTypes(ti)
+	} else {
+		astFile, data, err = Parse(data, filename)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return Inspect(astFile, data)
+	data, err = tagger.inspect(astFile, data, filename)
+	return data, withFilename(err, filename)
 }
 
 // Parse returns an *ast.File, the data parsed, and an error
@@ -203,220 +426,644 @@ func Parse(data []byte, filename string) (*ast.File, []byte, error) {
 	return f, data, err
 }
 
-// ProcessFile processes a file, returning the processed []byte
+// ProcessFile processes a file using the package level options and filters, returning the processed []byte
 func ProcessFile(path string) ([]byte, error) {
-	f, data, err := parseFile(path)
+	return defaultTagger().ProcessFile(path)
+}
+
+// ProcessFile processes a file, returning the processed []byte
+func (t *Tagger) ProcessFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return Inspect(f, data)
+	return t.ProcessBytes(data, path)
 }
 
-// parseFile reads all file information into a buffer, then creates a token set and parses the file, returning a *ast.File
-func parseFile(path string) (*ast.File, []byte, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, nil, err
+// withFilename sets the filename of every position in err to filename if err is a scanner.ErrorList without filenames,
+// which is what format.Source returns, so that errors always read as file:line:col
+func withFilename(err error, filename string) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		return err
 	}
-	name := filepath.Base(path)
-	return Parse(data, name)
+	for _, e := range list {
+		if e.Pos.Filename == "" {
+			e.Pos.Filename = filename
+		}
+	}
+	return list
 }
 
-// Inspect visits all nodes in the *ast.File (recursively), performing mutations on the buffer when the type found is an *ast.StructType
+// Inspect visits all nodes in the *ast.File using the package level options and filters. See Tagger.Inspect.
 func Inspect(f *ast.File, srcFileData []byte) ([]byte, error) {
-	data := srcFileData
-	var offset *int
-	offsetVal := 0
-	offset = &offsetVal
-	ast.Inspect(f, func(n ast.Node) bool {
-		switch t := n.(type) {
-		case *ast.Ident:
-			if t.Obj != nil {
-				if t.Obj.Kind == ast.Typ {
-					lastTypeName = t.Obj.Name
-				}
-			}
-		case *ast.Comment:
-			if strings.Contains(t.Text, options.GenerateTag) {
-				lastCommentWithGenerateTag = strings.TrimLeft(t.Text, `//`)
+	return defaultTagger().Inspect(f, srcFileData)
+}
+
+// inspection holds the state of a single call to Tagger.Edits
+type inspection struct {
+	*Tagger
+	file     *ast.File
+	src      []byte
+	filename string
+	edits    []Edit
+	stack    []ast.Node
+	err      error
+}
+
+// visit is called by ast.Inspect for every node in the file, collecting edits when the node is an *ast.StructType. It
+// keeps the path from the root of the file to the current node in the stack, so that structs can be named by where they
+// are declared and tagged with the comment directives above their declaration. The first malformed directive stops any
+// more structs from being tagged.
+func (in *inspection) visit(n ast.Node) bool {
+	if n == nil {
+		in.stack = in.stack[:len(in.stack)-1]
+		return true
+	}
+	in.stack = append(in.stack, n)
+	t, ok := n.(*ast.StructType)
+	if !ok || in.err != nil {
+		return true
+	}
+	tagger, err := in.structTagger()
+	if err == nil {
+		err = in.checkFieldDirectives(t)
+	}
+	if err != nil {
+		in.err = err
+		return true
+	}
+	name := StructName(in.stack)
+	in.edits = append(in.edits, tagger.TagStruct(in.file, in.src, name, t)...)
+	if tagger.Options.Operation == AddTags {
+		in.reportMultiNames(tagger, name, t)
+	}
+	return true
+}
+
+// StructName returns the name of the struct type at the end of path, which is the list of nodes from the root of the
+// file to the struct, as given by ast.Inspect. A struct declared with a TypeSpec has the name of the type, even when it
+// is declared inside a function. An anonymous struct that is the type of a field (or a pointer, slice, array, map or
+// channel of it) is named by its field path, such as Server.Config for the Config field of Server. Any other anonymous
+// struct, such as the type of a variable or a composite literal, has no name, and an empty string is returned.
+func StructName(path []ast.Node) string {
+	i := len(path) - 2
+	for ; i >= 0; i-- {
+		switch path[i].(type) {
+		case *ast.StarExpr, *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.ParenExpr:
+			continue
+		}
+		break
+	}
+	if i < 0 {
+		return ""
+	}
+
+	switch t := path[i].(type) {
+	case *ast.TypeSpec:
+		return t.Name.Name
+	case *ast.Field:
+		// only fields of a struct have a field path; the parameters and results of a func type do not
+		if len(t.Names) == 0 || i < 2 {
+			return ""
+		}
+		if _, ok := path[i-1].(*ast.FieldList); !ok {
+			return ""
+		}
+		if _, ok := path[i-2].(*ast.StructType); !ok {
+			return ""
+		}
+		if outer := StructName(path[:i-1]); outer != "" {
+			return outer + "." + t.Names[0].Name
+		}
+	}
+	return ""
+}
+
+// reportMultiNames warns about every field in the struct that has more than one name and would be tagged but is not
+// split, since they are skipped rather than given a tag that is wrong for all but the first name
+func (in *inspection) reportMultiNames(t *Tagger, structName string, s *ast.StructType) {
+	if !t.ShouldTagStruct(structName) {
+		return
+	}
+	for _, f := range s.Fields.List {
+		if len(f.Names) < 2 {
+			continue
+		}
+		inline := HasStructType(f.Type)
+		if t.Options.MultiNames == MultiSplit && !inline {
+			continue
+		}
+		names := make([]string, len(f.Names))
+		changed := false
+		for i, n := range f.Names {
+			names[i] = n.Name
+			if _, c := t.fieldTag(structName, n.Name, f); c {
+				changed = true
 			}
-		case *ast.StructType:
-			data = TagStruct(data, t, offset)
 		}
-		return true
+		switch {
+		case changed && inline:
+			sterrors.Warnf("%s: Field %s in %s has more than one name and a struct type, which cannot be split - Skipping Field (declare the struct as a named type to tag each name)\n",
+				in.position(f.Pos()), strings.Join(names, ", "), structName)
+		case changed:
+			sterrors.Warnf("%s: Field %s in %s has more than one name and they cannot share one tag - Skipping Field (use -multi=split to give each name its own tag)\n",
+				in.position(f.Pos()), strings.Join(names, ", "), structName)
+		}
+	}
+}
+
+// HasStructType checks if the type expression declares a struct anywhere in it, as in struct{ X int } or
+// []*struct{ X int }
+func HasStructType(typ ast.Expr) bool {
+	found := false
+	ast.Inspect(typ, func(n ast.Node) bool {
+		if _, ok := n.(*ast.StructType); ok {
+			found = true
+		}
+		return !found
 	})
+	return found
+}
+
+// position returns pos in the format file:line:col, or line:col if the filename is not known
+func (in *inspection) position(pos token.Pos) string {
+	line, col := lineCol(in.src, offset(in.file, pos))
+	if in.filename == "" {
+		return fmt.Sprintf("%d:%d", line, col)
+	}
+	return fmt.Sprintf("%s:%d:%d", in.filename, line, col)
+}
+
+// Edits returns the edits needed to tag the *ast.File using the package level options and filters. See Tagger.Edits.
+func Edits(f *ast.File, src []byte) ([]Edit, error) {
+	return defaultTagger().Edits(f, src)
+}
+
+// Edits visits all nodes in the *ast.File (recursively) and returns the edits needed to tag every *ast.StructType that
+// is found, sorted by position. The edits never overlap, and can be applied to src with ApplyEdits. An error is returned
+// if a comment directive in the file is malformed.
+func (t *Tagger) Edits(f *ast.File, src []byte) ([]Edit, error) {
+	return t.edits(f, src, "")
+}
+
+// edits returns the edits needed to tag the *ast.File, using filename in warnings and errors
+func (t *Tagger) edits(f *ast.File, src []byte, filename string) ([]Edit, error) {
+	in := &inspection{Tagger: t, file: f, src: src, filename: filename}
+	ast.Inspect(f, in.visit)
+	if in.err != nil {
+		return nil, in.err
+	}
+	return SortEdits(in.edits), nil
+}
+
+// Inspect applies the edits needed to tag the *ast.File to a copy of the source, and returns the formatted result
+func (t *Tagger) Inspect(f *ast.File, srcFileData []byte) ([]byte, error) {
+	return t.inspect(f, srcFileData, "")
+}
+
+// inspect applies the edits needed to tag the *ast.File to a copy of the source, using filename in warnings
+func (t *Tagger) inspect(f *ast.File, srcFileData []byte, filename string) ([]byte, error) {
+	edits, err := t.edits(f, srcFileData, filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ApplyEdits(srcFileData, edits)
+	if err != nil {
+		return nil, err
+	}
 	return format.Source(data)
 }
 
-// TagStruct tags a struct based on whether or not it is exported, is ignored, and what flags are provided at runtime
-func TagStruct(srcData []byte, s *ast.StructType, offset *int) []byte {
-	// If the last type name is one of our ignored structs, return immediately
-	if IsIgnoredTypeName(lastTypeName) {
-		return srcData
+// TagStruct returns the edits needed to tag the struct named structName in the file f, based on whether or not its
+// fields are exported, are ignored, and the Tagger's options
+func (t *Tagger) TagStruct(file *ast.File, src []byte, structName string, s *ast.StructType) []Edit {
+	// If the struct name is not one that we are tagging, return immediately
+	if !t.ShouldTagStruct(structName) {
+		return nil
 	}
+	var edits []Edit
 	for _, f := range s.Fields.List {
-		if len(f.Names) == 0 {
-			sterrors.Printf("Could not find name for field: %+v\n", f)
-			continue
-		}
-		if f.Names[0].IsExported() {
-			name := f.Names[0].Name
-			var formattedName string
-			if IsIgnoredField(name) {
-				formattedName = "-"
-			} else {
-				formattedName = FormatFieldName(name)
+		// a tag is shared by every name in a field, so a tag built from the first name would be wrong for the others
+		if len(f.Names) > 1 && t.Options.Operation == AddTags {
+			// the fields of a struct type are tagged on their own, and their edits would overlap the split
+			if t.Options.MultiNames != MultiSplit || HasStructType(f.Type) {
+				sterrors.Printf("Field %s.%s has more than one name - Skipping Field\n", structName, f.Names[0].Name)
+				continue
 			}
-			tag := f.Tag
-			if tag != nil {
-				val := tag.Value
-				// remove `'s from string and convert to a reflect.StructTag so we can use reflect.StructTag().Get() call
-				reflectTag := reflect.StructTag(val[1 : len(val)-1])
-				if options.AppendMode == SkipExisting || options.AppendMode == Append {
-					currentTagValue := reflectTag.Get(options.Tag)
-					if currentTagValue != "" {
-						sterrors.Printf("Existing tag found: TagName: %s, TagValue: %s, StartIndex: %d, EndIndex: %d - Skipping Tag\n", options.Tag, currentTagValue, tag.Pos(), tag.End())
-						continue
-					}
-				}
-				srcData = OverwriteStructTag(tag, formattedName, offset, srcData)
-			} else {
-				srcData = AppendStructTag(f, formattedName, offset, srcData)
+			if e, ok := t.splitFieldEdit(file, src, structName, f); ok {
+				edits = append(edits, e)
 			}
+			continue
 		}
+		name, ok := t.fieldName(f)
+		if !ok {
+			continue
+		}
+		newTag, changed := t.fieldTag(structName, name, f)
+		if !changed {
+			continue
+		}
+		switch {
+		case f.Tag == nil:
+			edits = append(edits, addTagEdit(file, f, newTag))
+		case newTag == "":
+			edits = append(edits, removeTagEdit(file, f))
+		default:
+			edits = append(edits, replaceTagEdit(file, f, newTag))
+		}
+	}
+	return edits
+}
 
+// fieldTag returns the contents of the tag (without backticks) for the field f when it is given the name name, and
+// whether or not it differs from the existing tag. Unexported names, names that are excluded by the Tagger's TagMode,
+// fields with an st:skip directive, and tags that cannot be parsed are never changed.
+func (t *Tagger) fieldTag(structName, name string, f *ast.Field) (string, bool) {
+	if !ast.IsExported(name) {
+		return "", false
+	}
+	if d, _, _ := ParseFieldDirectives(f); d.Skip {
+		sterrors.Printf("Field %s.%s has an st:skip directive - Skipping Field\n", structName, name)
+		return "", false
+	}
+	if !t.ShouldTagField(structName, name) {
+		sterrors.Printf("Field %s.%s is excluded by tag mode - Skipping Field\n", structName, name)
+		return "", false
 	}
-	return srcData
+	// removing and renaming only change existing tags, and leave ignored fields alone
+	if t.Options.Operation != AddTags && (f.Tag == nil || t.IsIgnoredField(name)) {
+		return "", false
+	}
+	var existing string
+	if f.Tag != nil {
+		// remove the quotes or `'s from the literal so we can parse the tag
+		var err error
+		existing, err = strconv.Unquote(f.Tag.Value)
+		if err != nil {
+			sterrors.Printf("Could not unquote tag for field %s.%s: %s - Skipping Field\n", structName, name, err)
+			return "", false
+		}
+	}
+	return t.updateStructTag(existing, structName, name, f)
 }
 
-// AppendStructTag adds an additional tag to a struct tag
-func AppendStructTag(field *ast.Field, tagName string, offset *int, data []byte) []byte {
-	start := int(field.End()) + *offset - 1
-	tag := fmt.Sprintf(" `%s:\"%s\"`", options.Tag, tagName)
-	*offset += len(tag)
-	return Insert(data, []byte(tag), start)
+// splitFieldEdit returns an edit that splits a field with more than one name, such as X, Y int, into one field for
+// each name, each with its own tag. Names that are not tagged keep the existing tag. It returns false if no name would
+// be tagged, in which case the field is left alone. The type of the field must not declare a struct, since the edits
+// that tag the fields of that struct would overlap the split.
+func (t *Tagger) splitFieldEdit(file *ast.File, src []byte, structName string, f *ast.Field) (Edit, bool) {
+	pos, end := offset(file, f.Pos()), offset(file, f.End())
+	typ := string(src[offset(file, f.Type.Pos()):offset(file, f.Type.End())])
+
+	var fields []string
+	changed := false
+	for _, n := range f.Names {
+		field := n.Name + " " + typ
+		newTag, c := t.fieldTag(structName, n.Name, f)
+		switch {
+		case c && newTag != "":
+			field += " " + tagLiteral(newTag)
+		case !c && f.Tag != nil:
+			field += " " + f.Tag.Value
+		}
+		changed = changed || c
+		fields = append(fields, field)
+	}
+	if !changed {
+		return Edit{}, false
+	}
+
+	// put each field on its own line with the same indentation, unless the struct is written on one line
+	sep := "; "
+	lineStart := bytes.LastIndexByte(src[:pos], '\n') + 1
+	if indent := src[lineStart:pos]; len(bytes.TrimSpace(indent)) == 0 {
+		sep = "\n" + string(indent)
+	}
+	return Edit{Pos: pos, End: end, Old: string(src[pos:end]), New: strings.Join(fields, sep)}, true
 }
 
-// OverwriteStructTag overwrites the struct tag completely
-func OverwriteStructTag(tag *ast.BasicLit, tagName string, offset *int, data []byte) []byte {
-	val := tag.Value
-	start := int(tag.Pos()) + *offset - 1
-	end := int(tag.End()) + *offset - 1
-	length := len(val)
-	oldLength := end - start
+// fieldName returns the name used to tag the field f, which is the type name for embedded fields, and false if the
+// field should be skipped. The Tagger's Embedded policy only decides whether embedded fields are tagged, so their
+// existing tags are always untagged, renamed and derived from, and the inline rule tags them even when the policy is
+// EmbeddedSkip.
+func (t *Tagger) fieldName(f *ast.Field) (string, bool) {
+	if len(f.Names) > 0 {
+		return f.Names[0].Name, true
+	}
+	name := EmbeddedTypeName(f.Type)
+	if name == "" {
+		sterrors.Printf("Could not find name for field: %+v\n", f)
+		return "", false
+	}
+	if t.Options.Embedded == EmbeddedSkip && t.Options.Operation == AddTags && !t.inlinesEmbedded() {
+		sterrors.Printf("Field %s is embedded - Skipping Field\n", name)
+		return "", false
+	}
+	return name, true
+}
 
-	// Delete the original tag
-	data = DeleteRange(data, start, end)
-	var newTag string
-	if options.AppendMode == Append {
-		oldTag := removeIndex(removeIndex(val, 0), len(val)-2)
-		newTag = fmt.Sprintf("`%s:\"%s\" %s`", options.Tag, tagName, oldTag)
-	} else {
-		newTag = fmt.Sprintf("`%s:\"%s\"`", options.Tag, tagName)
+// EmbeddedTypeName returns the name of the type of an embedded field, such as Base for *pkg.Base, or an empty string if
+// it cannot be found
+func EmbeddedTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return EmbeddedTypeName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
 	}
+	return ""
+}
 
-	numSpaces := len(newTag) - oldLength - 1
-	var spaces string
+// updateStructTag returns the contents of a struct tag (without backticks) for the field fieldName after applying the
+// Tagger's operation to existing, along with whether or not anything changed
+func (t *Tagger) updateStructTag(existing, structName, fieldName string, field *ast.Field) (string, bool) {
+	switch t.Options.Operation {
+	case RemoveTags:
+		return t.RemoveStructTag(existing, fieldName)
+	case RenameTags:
+		return t.RenameStructTag(existing, fieldName)
+	}
+	return t.BuildStructTag(existing, structName, fieldName, field)
+}
+
+// BuildStructTag returns the contents of a struct tag (without backticks) for the field fieldName in the struct
+// structName after applying every key in the Tagger's WrittenKeys() to existing, along with whether or not anything
+// changed. Each key honors the append mode on its own: keys that are already present are kept as they are unless the
+// mode is Overwrite or Update. Append mode places the new keys in front of the existing tag, SkipExisting keeps the
+// existing tag and adds the missing keys at the end, Overwrite replaces the tag with only the requested keys, and Update
+// replaces the name of each requested key in place, adding missing keys at the end and keeping keys that hide the field
+// with "-". The options that the Tagger's OptionRules give
+// for field are added to every key that is written, except for keys that are written with a template, which are always
+// replaced as a whole. field may be nil.
+func (t *Tagger) BuildStructTag(existing, structName, fieldName string, field *ast.Field) (string, bool) {
+	current, err := ParseStructTag(existing)
+	if err != nil {
+		sterrors.Printf("%s - Skipping Field %s\n", err, fieldName)
+		return existing, false
+	}
 
-	// Can't pass a negative number to strings.Repeat()
-	// it will cause a panic because it passes this number directly to make()
-	if numSpaces > 0 {
-		spaces = strings.Repeat(" ", numSpaces)
+	var tag StructTag
+	if t.Options.AppendMode == Update || t.Options.AppendMode == SkipExisting {
+		tag = append(tag, current...)
 	}
+	changed := false
+	for _, key := range t.Options.WrittenKeys() {
+		tagName, opts, ok := t.tagValue(key, structName, fieldName, field)
+		if !ok {
+			continue
+		}
+		pair := TagPair{Key: key, Value: tagName}.WithOptions(opts...)
+		i := current.Index(key)
 
-	newTag = fmt.Sprintf("%s%s", spaces, newTag)
-	localOffset := len(newTag) - length
-	*offset += localOffset
+		switch {
+		case t.Options.AppendMode == Update && i != -1:
+			if current[i].Name() == "-" && !t.IsIgnoredField(fieldName) {
+				// the field was hidden on purpose, and merging names must not expose it
+				continue
+			}
+			if tagName != "-" && t.Options.Template(key) == nil {
+				pair = TagPair{Key: key, Value: tagName + current[i].Options()}.WithOptions(opts...)
+			}
+			if pair.Value != current[i].Value {
+				tag[i] = pair
+				changed = true
+			}
+			continue
+		case t.Options.AppendMode != Overwrite && current.Get(key) != "":
+			sterrors.Printf("Existing tag found: TagName: %s, TagValue: %s - Skipping Tag\n", key, current.Get(key))
+			continue
+		}
+		tag = append(tag, pair)
+		changed = true
+	}
 
-	// Insert new tag
-	data = Insert(data, []byte(newTag), start)
-	return data
+	if t.Options.AppendMode == Append {
+		tag = append(tag, current...)
+	}
+	if t.Options.AppendMode == Overwrite {
+		changed = tag.String() != existing
+	}
+	if !changed {
+		return existing, false
+	}
+	return tag.String(), true
 }
 
-// IsIgnoredField checks if a field is an explicitly ignored field
-// Currently a slice is fine for performance, but we will replace these with maps later.
-func IsIgnoredField(s string) bool {
-	for _, f := range IgnoredFields {
-		if s == f {
-			return true
+// tagValue returns the name and options to write for the tag key on the field fieldName, and false if the key should not
+// be written at all. Embedded fields are given the key's inline marker when the Tagger's Embedded policy is
+// EmbeddedInline, and keys without a marker are not written. Otherwise they are given only the inline option for the
+// keys that the inline rule applies to, and other keys are not written unless the policy is EmbeddedName. Keys with a
+// template are given the result of the template. A name given with an st:name directive is written as it is, and is
+// used in place of the field name in templates.
+func (t *Tagger) tagValue(key, structName, fieldName string, field *ast.Field) (string, []string, bool) {
+	d, _, _ := ParseFieldDirectives(field)
+	if d.Name == "" && t.IsIgnoredField(fieldName) {
+		return "-", nil, true
+	}
+	if d.Name == "" && field != nil && IsEmbedded(field) && t.Options.Embedded == EmbeddedInline {
+		marker, ok := InlineMarkers[key]
+		return "", []string{marker}, ok
+	}
+	if d.Name == "" && field != nil && IsEmbedded(field) && t.inlinesEmbedded() {
+		if contains(t.FieldOptions(key, field), InlineOption) {
+			return "", []string{InlineOption}, true
+		}
+		if t.Options.Embedded == EmbeddedSkip {
+			return "", nil, false
 		}
 	}
-	return false
+	if tt := t.Options.Template(key); tt != nil {
+		name := fieldName
+		if d.Name != "" {
+			name = d.Name
+		}
+		data := NewTemplateData(structName, name, field, t.Options.Initialisms...)
+		if field != nil {
+			if typ := t.typeOf(field.Type); typ != nil {
+				data.SetType(typ, t.qualifier())
+			}
+		}
+		value, err := tt.Execute(data)
+		if err != nil {
+			sterrors.Printf("Could not execute template for tag %s on field %s.%s: %s - Skipping Tag\n", key, structName, fieldName, err)
+			return "", nil, false
+		}
+		if value == "" {
+			sterrors.Printf("Template for tag %s is empty for field %s.%s - Skipping Tag\n", key, structName, fieldName)
+			return "", nil, false
+		}
+		return value, nil, true
+	}
+	opts := appendMissing(t.FieldOptions(key, field), d.Options...)
+	if d.Name != "" {
+		return d.Name, opts, true
+	}
+	return t.FormatFieldName(key, fieldName), opts, true
 }
 
-// IsIgnoredTypeName checks if the name provided is an ignored struct
-func IsIgnoredTypeName(s string) bool {
-	for _, n := range IgnoredStructs {
-		if n == s {
-			return true
+// RemoveStructTag returns the contents of a struct tag (without backticks) for the field fieldName after removing every
+// key in the Tagger's TagKeys() from existing, along with whether or not anything changed. The result is empty if no
+// other keys are left.
+func (t *Tagger) RemoveStructTag(existing, fieldName string) (string, bool) {
+	current, err := ParseStructTag(existing)
+	if err != nil {
+		sterrors.Printf("%s - Skipping Field %s\n", err, fieldName)
+		return existing, false
+	}
+
+	var tag StructTag
+	keys := t.Options.TagKeys()
+	for _, p := range current {
+		if contains(keys, p.Key) {
+			sterrors.Printf("Removing tag: TagName: %s, TagValue: %s from Field %s\n", p.Key, p.Value, fieldName)
+			continue
 		}
+		tag = append(tag, p)
 	}
-	return false
+	if len(tag) == len(current) {
+		return existing, false
+	}
+	return tag.String(), true
 }
 
-// DeleteRange deletes a range from a []byte, returning the new slice
-func DeleteRange(data []byte, start, end int) []byte {
-	return append(data[:start], data[end:]...)
+// RenameStructTag returns the contents of a struct tag (without backticks) for the field fieldName after applying the
+// Tagger's RenameKeys and then its DeriveKeys to existing, along with whether or not anything changed. A renamed key
+// keeps its place in the tag, and a derived key is added at the end with the same value (and options) as the key it
+// is derived from. A key is never renamed or derived over a key that is already in the tag.
+func (t *Tagger) RenameStructTag(existing, fieldName string) (string, bool) {
+	tag, err := ParseStructTag(existing)
+	if err != nil {
+		sterrors.Printf("%s - Skipping Field %s\n", err, fieldName)
+		return existing, false
+	}
+
+	changed := false
+	for _, m := range t.Options.RenameKeys {
+		i := tag.Index(m.From)
+		if i == -1 {
+			continue
+		}
+		if tag.Index(m.To) != -1 {
+			sterrors.Printf("Existing tag found: TagName: %s, TagValue: %s - Not renaming %s for Field %s\n", m.To, tag.Get(m.To), m.From, fieldName)
+			continue
+		}
+		tag[i].Key = m.To
+		changed = true
+	}
+	for _, m := range t.Options.DeriveKeys {
+		i := tag.Index(m.From)
+		if i == -1 {
+			continue
+		}
+		if tag.Index(m.To) != -1 {
+			sterrors.Printf("Existing tag found: TagName: %s, TagValue: %s - Not deriving it from %s for Field %s\n", m.To, tag.Get(m.To), m.From, fieldName)
+			continue
+		}
+		tag = append(tag, TagPair{Key: m.To, Value: tag[i].Value})
+		changed = true
+	}
+	if !changed {
+		return existing, false
+	}
+	return tag.String(), true
 }
 
-// Insert inserts insertData into data at the given start index
-func Insert(data, insertData []byte, start int) []byte {
-	return append(data[:start], append(insertData, data[start:]...)...)
+// ShouldTagStruct checks if the struct with the given name should be tagged, depending on the Tagger's TagMode
+func (t *Tagger) ShouldTagStruct(name string) bool {
+	if t.IsIgnoredTypeName(name) {
+		return false
+	}
+	switch t.Options.TagMode {
+	case IncludeSpecifiedStructs:
+		return contains(t.Filters.IncludedStructs, name)
+	case IncludeStructAndFieldKeypairs:
+		for _, k := range t.Filters.IncludedKeypairs {
+			if structName, _ := SplitKeypair(k); structName == name {
+				return true
+			}
+		}
+		return false
+	}
+	return true
 }
 
-// removeIndex removes a single index from a string
-func removeIndex(input string, index int) string {
-	return input[:index] + input[index+1:]
+// ShouldTagField checks if the field in the given struct should be tagged, depending on the Tagger's TagMode
+func (t *Tagger) ShouldTagField(structName, fieldName string) bool {
+	keypair := structName + "." + fieldName
+	switch t.Options.TagMode {
+	case SkipStructAndFieldKeypairs:
+		return !contains(t.Filters.SkippedKeypairs, keypair)
+	case IncludeStructAndFieldKeypairs:
+		return contains(t.Filters.IncludedKeypairs, keypair)
+	}
+	return true
 }
 
-// FormatFieldName formats the field name as either CamelCase or snake_case
-func FormatFieldName(n string) string {
-	switch options.Case {
-	case Camel:
-		return CamelCase(n)
-	case Snake:
-		return Underscore(n)
+// SplitKeypair splits a Struct.Field keypair into its struct and field names. If there is no "." in the keypair,
+// both names will be empty.
+func SplitKeypair(keypair string) (string, string) {
+	i := strings.LastIndex(keypair, ".")
+	if i < 1 || i == len(keypair)-1 {
+		return "", ""
 	}
-	sterrors.Printf("Could not format string, Case is not set.\n")
-	return n
+	return keypair[:i], keypair[i+1:]
 }
 
-var (
-	doubleColon       = regexp.MustCompile("::")
-	dash              = regexp.MustCompile("-")
-	uppersOrNumsLower = regexp.MustCompile("([A-Z0-9]+)([A-Z][a-z])")
-	lowerUpper        = regexp.MustCompile("([a-z])([A-Z0-9])")
-)
+// contains checks if s is in list
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIgnoredField checks if a field is in the package level IgnoredFields
+func IsIgnoredField(s string) bool {
+	return defaultTagger().IsIgnoredField(s)
+}
 
-// Underscore will change a string from a camelcased
-// form to a string with underscores. Will change "::" to
-// "/" to maintain compatibility with Rails's underscore
-func Underscore(str string) string {
-	output := doubleColon.ReplaceAllString(str, "/")
+// IsIgnoredField checks if a field is an explicitly ignored field
+// Currently a slice is fine for performance, but we will replace these with maps later.
+func (t *Tagger) IsIgnoredField(s string) bool {
+	return contains(t.Filters.IgnoredFields, s)
+}
 
-	// Rails uses underscores while I use dashes in this function
-	// Go's regexp doesn't like $1_$2, so we'll use a dash instead
-	// since it will get fixed in a later replacement
-	output = uppersOrNumsLower.ReplaceAllString(output, "$1-$2")
-	output = lowerUpper.ReplaceAllString(output, "$1-$2")
+// IsIgnoredTypeName checks if the name provided is in the package level IgnoredStructs
+func IsIgnoredTypeName(s string) bool {
+	return defaultTagger().IsIgnoredTypeName(s)
+}
 
-	output = strings.ToLower(output)
-	output = dash.ReplaceAllString(output, "_")
+// IsIgnoredTypeName checks if the name provided is an ignored struct
+func (t *Tagger) IsIgnoredTypeName(s string) bool {
+	return contains(t.Filters.IgnoredStructs, s)
+}
 
-	return output
+// DeleteRange deletes a range from a []byte, returning the new slice
+func DeleteRange(data []byte, start, end int) []byte {
+	return append(data[:start], data[end:]...)
+}
+
+// Insert inserts insertData into data at the given start index
+func Insert(data, insertData []byte, start int) []byte {
+	return append(data[:start], append(insertData, data[start:]...)...)
 }
 
-// Taken from https://github.com/etgryphon/stringUp/blob/master/stringUp.go
-var camelingRegex = regexp.MustCompile("[0-9A-Za-z]+")
+// FormatFieldName formats the field name for the given tag key using the package level options. See Tagger.FormatFieldName.
+func FormatFieldName(tag, n string) string {
+	return defaultTagger().FormatFieldName(tag, n)
+}
 
-// CamelCase converts a string to the CamelCase version of it
-func CamelCase(src string) string {
-	byteSrc := []byte(src)
-	chunks := camelingRegex.FindAll(byteSrc, -1)
-	for idx, val := range chunks {
-		if idx > 0 {
-			chunks[idx] = bytes.Title(val)
-		}
+// FormatFieldName formats the field name for the given tag key, using the case set for that key in Options.Cases
+// or Options.Case if the key has no case of its own, and keeping Options.Initialisms together
+func (t *Tagger) FormatFieldName(tag, n string) string {
+	c, ok := t.Options.Cases[tag]
+	if !ok {
+		c = t.Options.Case
 	}
-	return string(bytes.Join(chunks, nil))
+	return FormatName(n, c, t.Options.Initialisms...)
 }
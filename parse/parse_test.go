@@ -1,13 +1,19 @@
 package parse
 
 import (
+	"bytes"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/alistanis/st/sterrors"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -39,14 +45,14 @@ type TestStruct struct {
 
 type TestStruct struct {
 	Int             int               %sjson:"int"%s
-	Int64           int64             %sjson:"int_64"%s
+	Int64           int64             %sjson:"int64"%s
 	IntSlice        []int             %sjson:"int_slice"%s
-	Int64Slice      []int64           %sjson:"int_64_slice"%s
+	Int64Slice      []int64           %sjson:"int64_slice"%s
 	String          string            %sjson:"string"%s
 	StringSlice     []string          %sjson:"string_slice"%s
 	Float           float64           %sjson:"float"%s
 	FloatSlice      []float64         %sjson:"float_slice"%s
-	UIntPointer     uintptr           %sjson:"u_int_pointer"%s
+	UIntPointer     uintptr           %sjson:"uint_pointer"%s
 	Rune            rune              %sjson:"rune"%s
 	RuneSlice       []rune            %sjson:"rune_slice"%s
 	Byte            byte              %sjson:"byte"%s
@@ -59,22 +65,22 @@ type TestStruct struct {
 	camelTestDataExistingTags = strings.Replace(`package test
 
 type TestStruct struct {
-	Int             int               %sjson:"Int"%s
-	Int64           int64             %sjson:"Int64"%s
-	IntSlice        []int             %sjson:"IntSlice"%s
-	Int64Slice      []int64           %sjson:"Int64Slice"%s
-	String          string            %sjson:"String"%s
-	StringSlice     []string          %sjson:"StringSlice"%s
-	Float           float64           %sjson:"Float"%s
-	FloatSlice      []float64         %sjson:"FloatSlice"%s
-	UIntPointer     uintptr           %sjson:"UIntPointer"%s
-	Rune            rune              %sjson:"Rune"%s
-	RuneSlice       []rune            %sjson:"RuneSlice"%s
-	Byte            byte              %sjson:"Byte"%s
-	ByteSlice       []byte            %sjson:"ByteSlice"%s
-	MapStringString map[string]string %sjson:"MapStringString"%s
-	MapStringInt    map[string]int    %sjson:"MapStringInt"%s
-	MapIntString    map[int]string    %sjson:"MapIntString"%s
+	Int             int               %sjson:"int"%s
+	Int64           int64             %sjson:"int64"%s
+	IntSlice        []int             %sjson:"intSlice"%s
+	Int64Slice      []int64           %sjson:"int64Slice"%s
+	String          string            %sjson:"string"%s
+	StringSlice     []string          %sjson:"stringSlice"%s
+	Float           float64           %sjson:"float"%s
+	FloatSlice      []float64         %sjson:"floatSlice"%s
+	UIntPointer     uintptr           %sjson:"uintPointer"%s
+	Rune            rune              %sjson:"rune"%s
+	RuneSlice       []rune            %sjson:"runeSlice"%s
+	Byte            byte              %sjson:"byte"%s
+	ByteSlice       []byte            %sjson:"byteSlice"%s
+	MapStringString map[string]string %sjson:"mapStringString"%s
+	MapStringInt    map[string]int    %sjson:"mapStringInt"%s
+	MapIntString    map[int]string    %sjson:"mapIntString"%s
 }
 `, "%s", "`", -1)
 
@@ -86,6 +92,13 @@ type TestStruct struct {
 `
 	appendData = strings.Replace(`package test
 
+type TestStruct struct {
+	Field string %sjson:"field"%s
+}
+`, "%s", "`", -1)
+
+	expectedWrittenData = strings.Replace(`package test
+
 type TestStruct struct {
 	Field string %sjson:"field"%s
 }
@@ -142,6 +155,545 @@ func TestSnakeCase(t *testing.T) {
 	})
 }
 
+func TestMultipleTags(t *testing.T) {
+	Convey("Given a list of tags to apply in a single pass", t, func() {
+		opts := DefaultOptions()
+		opts.Tags = []string{"json", "yaml", "db"}
+		SetOptions(opts)
+		Convey("We can add every tag to a field that has no tags", func() {
+			data, err := ProcessBytes([]byte(ignoredStructData), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Field string %sjson:"field" yaml:"field" db:"field"%s
+}
+`, "%s", "`", -1))
+		})
+		Convey("Each tag honors append mode on its own", func() {
+			options.AppendMode = Append
+			data, err := ProcessBytes([]byte(appendData), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Field string %syaml:"field" db:"field" json:"field"%s
+}
+`, "%s", "`", -1))
+		})
+		Convey("Each tag honors skip existing mode on its own", func() {
+			data, err := ProcessBytes([]byte(strings.Replace(`package test
+
+type TestStruct struct {
+	Field string %sjson:"Field"%s
+}
+`, "%s", "`", -1)), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Field string %sjson:"Field" yaml:"field" db:"field"%s
+}
+`, "%s", "`", -1))
+		})
+		Convey("Skip existing mode keeps the keys that were not requested", func() {
+			options.Tags = []string{"json", "db"}
+			options.Cases = map[string]string{"json": Camel}
+			data, err := ProcessBytes([]byte(strings.Replace(`package test
+
+type TestStruct struct {
+	Name string %sjson:"name,omitempty" xml:"name"%s
+}
+`, "%s", "`", -1)), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Name string %sjson:"name,omitempty" xml:"name" db:"name"%s
+}
+`, "%s", "`", -1))
+		})
+		Convey("Overwrite mode replaces every tag", func() {
+			options.AppendMode = Overwrite
+			data, err := ProcessBytes([]byte(expectedAppendData), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Field string %sjson:"field" yaml:"field" db:"field"%s
+}
+`, "%s", "`", -1))
+		})
+	})
+}
+
+func TestTagCases(t *testing.T) {
+	Convey("Given a case for each tag", t, func() {
+		opts := DefaultOptions()
+		opts.Tags = []string{"json", "db"}
+		opts.Cases = map[string]string{"json": Camel, "db": Snake}
+		SetOptions(opts)
+		Convey("Each tag is formatted with its own case", func() {
+			data, err := ProcessBytes([]byte(`package test
+
+type TestStruct struct {
+	UserName string
+}
+`), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	UserName string %sjson:"userName" db:"user_name"%s
+}
+`, "%s", "`", -1))
+		})
+		Convey("Tags without a case of their own use the default case", func() {
+			delete(options.Cases, "db")
+			options.Case = Camel
+			So(FormatFieldName("db", "UserName"), ShouldEqual, "userName")
+			options.Case = Snake
+			So(FormatFieldName("db", "UserName"), ShouldEqual, "user_name")
+		})
+	})
+}
+
+func TestMerge(t *testing.T) {
+	Convey("Given a field with an existing tag that has options and other keys", t, func() {
+		opts := DefaultOptions()
+		opts.AppendMode = Update
+		opts.Case = Camel
+		SetOptions(opts)
+		src := strings.Replace(`package test
+
+type TestStruct struct {
+	UserName string %sdb:"user_name" json:"user_name,omitempty" yaml:"user"%s
+	Missing  string %sdb:"missing"%s
+}
+`, "%s", "`", -1)
+
+		Convey("Only the name of the requested key is replaced, keeping its options and every other key in order", func() {
+			data, err := ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	UserName string %sdb:"user_name" json:"userName,omitempty" yaml:"user"%s
+	Missing  string %sdb:"missing" json:"missing"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Nothing changes when the names are already correct", func() {
+			options.Case = Snake
+			options.Tags = []string{"db"}
+			data, err := ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, src)
+		})
+		Convey("Fields that are hidden with - stay hidden unless they are ignored", func() {
+			hidden := strings.Replace(`package test
+
+type TestStruct struct {
+	Password string %sjson:"-" db:"password"%s
+}
+`, "%s", "`", -1)
+			data, err := ProcessBytes([]byte(hidden), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, hidden)
+
+			options.Tags = []string{"json", "db"}
+			data, err = NewTagger(options, Filters{IgnoredFields: []string{"Password"}}).ProcessBytes([]byte(hidden), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(hidden, `db:"password"`, `db:"-"`, 1))
+		})
+	})
+}
+
+func TestUntag(t *testing.T) {
+	Convey("Given structs with several tags", t, func() {
+		opts := DefaultOptions()
+		opts.Operation = RemoveTags
+		opts.Tags = []string{"xml", "bson"}
+		src := strings.Replace(`package test
+
+type TestStruct struct {
+	Name     string %sjson:"name" xml:"name"%s
+	Email    string %sxml:"email" bson:"email,omitempty"%s // only deprecated tags
+	Password string %sxml:"-"%s
+	Missing  string %sjson:"missing"%s
+	NoTag    string
+}
+
+type Skipped struct {
+	Name string %sxml:"name"%s
+}
+`, "%s", "`", -1)
+
+		Convey("The keys are removed from every field, and tags that become empty are removed altogether", func() {
+			data, err := NewTagger(opts, Filters{IgnoredStructs: []string{"Skipped"}, IgnoredFields: []string{"Password"}}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Name     string %sjson:"name"%s
+	Email    string // only deprecated tags
+	Password string %sxml:"-"%s
+	Missing  string %sjson:"missing"%s
+	NoTag    string
+}
+
+type Skipped struct {
+	Name string %sxml:"name"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Keys are removed from embedded fields whatever the embedded policy is", func() {
+			opts.Tags = []string{"xml", "yaml"}
+			embedded := strings.Replace(`package test
+
+type TestStruct struct {
+	Base  %sjson:"base" yaml:",inline" xml:"base"%s
+	*Other %syaml:",inline"%s
+}
+`, "%s", "`", -1)
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(embedded), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Base %sjson:"base"%s
+	*Other
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Removing keys that are not present changes nothing", func() {
+			opts.Tags = []string{"yaml"}
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, src)
+		})
+	})
+}
+
+func TestRename(t *testing.T) {
+	Convey("Given structs with existing tags", t, func() {
+		opts := DefaultOptions()
+		opts.Operation = RenameTags
+		src := strings.Replace(`package test
+
+type TestStruct struct {
+	ID       string %sjson:"id" bson:"_id,omitempty"%s
+	Name     string %sbson:"name" json:"name"%s
+	Conflict string %sbson:"conflict" mongo:"other"%s
+	NoTag    string
+}
+`, "%s", "`", -1)
+
+		Convey("Renamed keys keep their place, values and options", func() {
+			opts.RenameKeys = []KeyMapping{{From: "bson", To: "mongo"}}
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	ID       string %sjson:"id" mongo:"_id,omitempty"%s
+	Name     string %smongo:"name" json:"name"%s
+	Conflict string %sbson:"conflict" mongo:"other"%s
+	NoTag    string
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Derived keys are added at the end with the value of their source", func() {
+			opts.DeriveKeys = []KeyMapping{{From: "bson", To: "yaml"}}
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	ID       string %sjson:"id" bson:"_id,omitempty" yaml:"_id,omitempty"%s
+	Name     string %sbson:"name" json:"name" yaml:"name"%s
+	Conflict string %sbson:"conflict" mongo:"other" yaml:"conflict"%s
+	NoTag    string
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Keys are renamed before new keys are derived", func() {
+			opts.RenameKeys = []KeyMapping{{From: "json", To: "yaml"}}
+			opts.DeriveKeys = []KeyMapping{{From: "yaml", To: "json"}}
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "`yaml:\"id\" bson:\"_id,omitempty\" json:\"id\"`")
+		})
+		Convey("Keys are renamed and derived on embedded fields whatever the embedded policy is", func() {
+			opts.RenameKeys = []KeyMapping{{From: "xml", To: "bson"}}
+			opts.DeriveKeys = []KeyMapping{{From: "bson", To: "mongo"}}
+			embedded := strings.Replace(`package test
+
+type TestStruct struct {
+	Base %syaml:",inline" xml:"base"%s
+}
+`, "%s", "`", -1)
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(embedded), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "Base `yaml:\",inline\" bson:\"base\" mongo:\"base\"`")
+		})
+	})
+}
+
+func TestEmbedded(t *testing.T) {
+	Convey("Given a struct with embedded fields", t, func() {
+		opts := DefaultOptions()
+		opts.Tags = []string{"json", "yaml", "mapstructure"}
+		src := `package test
+
+type TestStruct struct {
+	Base
+	*pkg.Config
+	base
+	Name string
+}
+`
+
+		Convey("Embedded fields are skipped by default", func() {
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Base
+	*pkg.Config
+	base
+	Name string %sjson:"name" yaml:"name" mapstructure:"name"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Exported embedded fields can be tagged with the name of their type", func() {
+			opts.Embedded = EmbeddedName
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Base        %sjson:"base" yaml:"base" mapstructure:"base"%s
+	*pkg.Config %sjson:"config" yaml:"config" mapstructure:"config"%s
+	base
+	Name string %sjson:"name" yaml:"name" mapstructure:"name"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Embedded fields can be inlined, leaving json untagged", func() {
+			opts.Embedded = EmbeddedInline
+			data, err := NewTagger(opts, Filters{SkippedKeypairs: []string{"TestStruct.Config"}}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "\tBase        `yaml:\",inline\" mapstructure:\",squash\"`\n")
+
+			Convey("And the Struct.Field filters use the type name", func() {
+				opts.TagMode = SkipStructAndFieldKeypairs
+				data, err := NewTagger(opts, Filters{SkippedKeypairs: []string{"TestStruct.Config"}}).ProcessBytes([]byte(src), "test.go")
+				So(err, ShouldBeNil)
+				So(string(data), ShouldContainSubstring, "\t*pkg.Config\n")
+			})
+		})
+	})
+
+	Convey("Given the inline option rule", t, func() {
+		opts := DefaultOptions()
+		opts.Tags = []string{"json", "yaml", "bson"}
+		rule, _ := FindOptionRule(InlineOption)
+		opts.OptionRules = []OptionRule{rule}
+		src := "package test\n\ntype TestStruct struct {\n\tBase\n\t*Other\n\tName string\n}\n"
+
+		Convey("Embedded fields are inlined for yaml and bson only, even though the policy skips them", func() {
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	Base   %syaml:",inline" bson:",inline"%s
+	*Other %syaml:",inline" bson:",inline"%s
+	Name   string %sjson:"name" yaml:"name" bson:"name"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Other keys use the type name when the policy names embedded fields", func() {
+			opts.Embedded = EmbeddedName
+			opts.OptionRules[0].Keys = []string{"yaml"}
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "\tBase   `json:\"base\" yaml:\",inline\" bson:\"base\"`\n")
+		})
+	})
+
+	Convey("We can find the type name of embedded fields", t, func() {
+		for expr, name := range map[string]string{"Base": "Base", "*Base": "Base", "pkg.Base": "Base", "*pkg.Base": "Base", "[]Base": ""} {
+			e, err := parser.ParseExpr(expr)
+			So(err, ShouldBeNil)
+			So(EmbeddedTypeName(e), ShouldEqual, name)
+		}
+	})
+}
+
+func TestMultiNames(t *testing.T) {
+	Convey("Given a struct with fields that have more than one name", t, func() {
+		opts := DefaultOptions()
+		src := strings.Replace(`package test
+
+type TestStruct struct {
+	// X and Y are coordinates
+	X, Y    int // in pixels
+	A, b, C string %sdb:"shared"%s
+	Tagged  int
+}
+
+type Inline struct{ First, Last string }
+`, "%s", "`", -1)
+
+		Convey("They are reported and skipped by default", func() {
+			var buf bytes.Buffer
+			sterrors.Warnings = &buf
+			defer func() { sterrors.Warnings = os.Stderr }()
+
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "\tX, Y    int    // in pixels\n")
+			So(string(data), ShouldContainSubstring, "\tTagged  int    `json:\"tagged\"`\n")
+			So(buf.String(), ShouldEqual, strings.Join([]string{
+				"test.go:5:2: Field X, Y in TestStruct has more than one name and they cannot share one tag - Skipping Field (use -multi=split to give each name its own tag)",
+				"test.go:6:2: Field A, b, C in TestStruct has more than one name and they cannot share one tag - Skipping Field (use -multi=split to give each name its own tag)",
+				"test.go:10:21: Field First, Last in Inline has more than one name and they cannot share one tag - Skipping Field (use -multi=split to give each name its own tag)",
+				""}, "\n"))
+		})
+
+		Convey("They can be split into one field for each name", func() {
+			opts.MultiNames = MultiSplit
+			opts.AppendMode = Append
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	// X and Y are coordinates
+	X      int    %sjson:"x"%s
+	Y      int    %sjson:"y"%s // in pixels
+	A      string %sjson:"a" db:"shared"%s
+	b      string %sdb:"shared"%s
+	C      string %sjson:"c" db:"shared"%s
+	Tagged int    %sjson:"tagged"%s
+}
+
+type Inline struct {
+	First string %sjson:"first"%s
+	Last  string %sjson:"last"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Fields with a struct type are reported and skipped when splitting, and their struct is still tagged", func() {
+			var buf bytes.Buffer
+			sterrors.Warnings = &buf
+			defer func() { sterrors.Warnings = os.Stderr }()
+
+			opts.MultiNames = MultiSplit
+			src := "package test\n\ntype Pair struct {\n\tA, B struct{ X int }\n}\n"
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "\tA, B struct {\n\t\tX int `json:\"x\"`\n\t}\n")
+			So(buf.String(), ShouldEqual, "test.go:4:2: Field A, B in Pair has more than one name and a struct type, which cannot be split - Skipping Field (declare the struct as a named type to tag each name)\n")
+		})
+	})
+}
+
+func TestStructNames(t *testing.T) {
+	Convey("Given structs declared in different places", t, func() {
+		src := `package test
+
+type Server struct {
+	Config struct {
+		TLS *struct {
+			Cert string
+		}
+	}
+	Routes  []struct{ Path string }
+	Handler func(struct{ Req string }) struct{ Res string }
+}
+
+var global struct{ Global string }
+
+func f() {
+	type Local struct{ Local string }
+	_ = struct{ Literal string }{}
+}
+`
+		f, _, err := Parse([]byte(src), "test.go")
+		So(err, ShouldBeNil)
+
+		var names []string
+		var path []ast.Node
+		ast.Inspect(f, func(n ast.Node) bool {
+			if n == nil {
+				path = path[:len(path)-1]
+				return true
+			}
+			path = append(path, n)
+			if _, ok := n.(*ast.StructType); ok {
+				names = append(names, StructName(path))
+			}
+			return true
+		})
+
+		Convey("Each struct is named by its TypeSpec or field path", func() {
+			So(names, ShouldResemble, []string{"Server", "Server.Config", "Server.Config.TLS", "Server.Routes", "", "", "", "Local", ""})
+		})
+	})
+
+	Convey("Given a struct with anonymous struct fields", t, func() {
+		src := `package test
+
+type Server struct {
+	Config struct {
+		Port int
+	}
+	Name string
+}
+
+type Config struct {
+	Port int
+}
+`
+
+		Convey("Struct filters refer to the field path of nested structs", func() {
+			data, err := NewTagger(nil, Filters{IgnoredStructs: []string{"Config"}}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "\t\tPort int `json:\"port\"`\n")
+			So(string(data), ShouldContainSubstring, "type Config struct {\n\tPort int\n}")
+
+			data, err = NewTagger(nil, Filters{IgnoredStructs: []string{"Server.Config"}}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "\t\tPort int\n")
+			So(string(data), ShouldContainSubstring, "\tName string `json:\"name\"`\n")
+			So(string(data), ShouldContainSubstring, "type Config struct {\n\tPort int `json:\"port\"`\n}")
+		})
+
+		Convey("Struct.Field keypairs refer to the field path of nested structs", func() {
+			opts := DefaultOptions()
+			opts.TagMode = IncludeStructAndFieldKeypairs
+			data, err := NewTagger(opts, Filters{IncludedKeypairs: []string{"Server.Config.Port"}}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "\t\tPort int `json:\"port\"`\n")
+			So(string(data), ShouldContainSubstring, "\tName string\n")
+			So(string(data), ShouldContainSubstring, "type Config struct {\n\tPort int\n}")
+		})
+	})
+}
+
 func TestCamelCase(t *testing.T) {
 	Convey("Given sample code with multiple types of structs with tags/no tags", t, func() {
 		opts := DefaultOptions()
@@ -209,6 +761,151 @@ type TestStruct struct {
 
 	})
 }
+
+const tagModeData = `package test
+
+type User struct {
+	Name     string
+	Password string
+}
+
+type Order struct {
+	ID string
+}
+`
+
+func TestTagModes(t *testing.T) {
+	Convey("Given sample code with multiple structs", t, func() {
+		opts := DefaultOptions()
+		SetOptions(opts)
+		IgnoredFields = []string{}
+		IgnoredStructs = []string{}
+
+		Convey("We can tag only the specified structs", func() {
+			options.TagMode = IncludeSpecifiedStructs
+			IncludedStructs = []string{"Order"}
+			data, err := ProcessBytes([]byte(tagModeData), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type User struct {
+	Name     string
+	Password string
+}
+
+type Order struct {
+	ID string %sjson:"id"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("We can skip the specified Struct.Field keypairs", func() {
+			options.TagMode = SkipStructAndFieldKeypairs
+			SkippedKeypairs = []string{"User.Password"}
+			data, err := ProcessBytes([]byte(tagModeData), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type User struct {
+	Name     string %sjson:"name"%s
+	Password string
+}
+
+type Order struct {
+	ID string %sjson:"id"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("We can tag only the specified Struct.Field keypairs", func() {
+			options.TagMode = IncludeStructAndFieldKeypairs
+			IncludedKeypairs = []string{"User.Name"}
+			data, err := ProcessBytes([]byte(tagModeData), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type User struct {
+	Name     string %sjson:"name"%s
+	Password string
+}
+
+type Order struct {
+	ID string
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Ignored structs are skipped in every tag mode", func() {
+			options.TagMode = IncludeSpecifiedStructs
+			IncludedStructs = []string{"Order"}
+			IgnoredStructs = []string{"Order"}
+			data, err := ProcessBytes([]byte(tagModeData), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, tagModeData)
+		})
+
+		Reset(func() {
+			IgnoredStructs = []string{}
+			IncludedStructs = []string{}
+			SkippedKeypairs = []string{}
+			IncludedKeypairs = []string{}
+		})
+	})
+
+	Convey("We can split a keypair into its struct and field names", t, func() {
+		structName, fieldName := SplitKeypair("User.Password")
+		So(structName, ShouldEqual, "User")
+		So(fieldName, ShouldEqual, "Password")
+		structName, fieldName = SplitKeypair("Password")
+		So(structName, ShouldEqual, "")
+		So(fieldName, ShouldEqual, "")
+	})
+}
+
+func TestTagger(t *testing.T) {
+	Convey("Given taggers with their own options and filters", t, func() {
+		snake := NewTagger(nil, Filters{})
+		camelOpts := DefaultOptions()
+		camelOpts.Case = Camel
+		camel := NewTagger(camelOpts, Filters{})
+		ignoring := NewTagger(nil, Filters{IgnoredStructs: []string{"TestStruct"}})
+
+		Convey("A nil *Options uses the default options", func() {
+			So(snake.Options, ShouldResemble, DefaultOptions())
+		})
+
+		Convey("Each tagger uses its own filters", func() {
+			data, err := ignoring.ProcessBytes([]byte(ignoredStructData), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, ignoredStructData)
+		})
+
+		Convey("We can use the taggers from many goroutines at once", func() {
+			type result struct {
+				data, expected string
+				err            error
+			}
+			results := make(chan result)
+			n := 20
+			for i := 0; i < n; i++ {
+				tagger, expected := snake, snakeTestDataExistingTags
+				if i%2 == 0 {
+					tagger, expected = camel, camelTestDataExistingTags
+				}
+				go func(tagger *Tagger, expected string) {
+					data, err := tagger.ProcessBytes([]byte(testDataNoExistingTags), "test.go")
+					results <- result{data: string(data), expected: expected, err: err}
+				}(tagger, expected)
+			}
+			for i := 0; i < n; i++ {
+				r := <-results
+				So(r.err, ShouldBeNil)
+				So(r.data, ShouldEqual, r.expected)
+			}
+		})
+	})
+}
+
 func TestFiles(t *testing.T) {
 	Convey("Given a temporary directory and temporary files", t, func() {
 
@@ -292,6 +989,26 @@ func TestFiles(t *testing.T) {
 
 		})
 
+		Convey("Given a directory", func() {
+			dir, err := ioutil.TempDir(tempDir, "")
+			So(err, ShouldBeNil)
+			err = ioutil.WriteFile(filepath.Join(dir, "test.go"), []byte(testDataNoExistingTags), 0664)
+			So(err, ShouldBeNil)
+			opts := DefaultOptions()
+			opts.DryRun = false
+			SetOptions(opts)
+			err = AndProcessFiles([]string{dir})
+			So(err, ShouldBeNil)
+			data, err := ioutil.ReadFile(filepath.Join(dir, "test.go"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, snakeTestDataExistingTags)
+
+			Reset(func() {
+				err = os.RemoveAll(dir)
+				So(err, ShouldBeNil)
+			})
+		})
+
 		Convey("Given a set of 'files' (as defined in parse.go)", func() {
 			files := []*File{{FileName: "test.go", Data: []byte(testDataNoExistingTags)}}
 			results, err := Process(files)
@@ -335,16 +1052,254 @@ type TestStruct struct {
 func TestGoGenerate(t *testing.T) {
 	Convey("Given a test string with a go generate tag", t, func() {
 		data, err := ProcessBytes([]byte(goGenCommentData), "test.go")
-		So(data, ShouldNotBeNil)
 		So(err, ShouldBeNil)
-		So(lastCommentWithGenerateTag, ShouldEqual, "@st -tag-name=msgpack")
+		So(string(data), ShouldContainSubstring, "Field string `msgpack:\"field\"`")
+
+		Convey("The directive is still a directive after gofmt adds a space to it", func() {
+			again, err := ProcessBytes(data, "test.go")
+			So(err, ShouldBeNil)
+			So(string(again), ShouldEqual, string(data))
+		})
 	})
 
-	Convey("We can obtain a new comment directive by providing a legitimate test source", t, func() {
-		cd, err := NewCommentDirective(goGenCommentData)
+	Convey("We can obtain a new comment directive without changing os.Args", t, func() {
+		args := append([]string(nil), os.Args...)
+		cd, err := NewCommentDirective(`//@st -t yaml -case camel -template='gorm:"column:{{.Snake}}"'`)
 		So(err, ShouldBeNil)
-		So(cd, ShouldNotBeNil)
-		So(len(cd.Args()), ShouldBeGreaterThan, 0)
+		So(os.Args, ShouldResemble, args)
+		So(cd.tag, ShouldEqual, "yaml")
+		So(cd.caseName, ShouldEqual, Camel)
+		So([]string(cd.templates), ShouldResemble, []string{`gorm:"column:{{.Snake}}"`})
+		So(len(cd.Args()), ShouldEqual, 0)
+	})
+
+	Convey("Only comments that start with the generate tag are directives", t, func() {
+		So(IsCommentDirective("//@st -t=yaml", DefaultGenerateTag), ShouldBeTrue)
+		So(IsCommentDirective("//@st", DefaultGenerateTag), ShouldBeTrue)
+		So(IsCommentDirective("// @st -t=yaml", DefaultGenerateTag), ShouldBeTrue)
+		So(IsCommentDirective("//@stop", DefaultGenerateTag), ShouldBeFalse)
+		So(IsCommentDirective("// see @st", DefaultGenerateTag), ShouldBeFalse)
+		So(IsCommentDirective("//@st -t=yaml", ""), ShouldBeFalse)
+	})
+
+	Convey("Given structs with directives", t, func() {
+		src := `package test
+
+//@st -t=yaml,db -case=camel
+type Directed struct {
+	UserName string
+	Inner    struct {
+		InnerName string
+	}
+}
+
+type (
+	//@st -i=Password
+	Grouped struct {
+		Password string
+		Name     string
+	}
+
+	Plain struct {
+		UserName string
+	}
+)
+`
+		Convey("Each directive only changes the options for the structs declared with it", func() {
+			data, err := NewTagger(nil, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+// @st -t=yaml,db -case=camel
+type Directed struct {
+	UserName string %syaml:"userName" db:"userName"%s
+	Inner    struct {
+		InnerName string %syaml:"innerName" db:"innerName"%s
+	} %syaml:"inner" db:"inner"%s
+}
+
+type (
+	//@st -i=Password
+	Grouped struct {
+		Password string %sjson:"-"%s
+		Name     string %sjson:"name"%s
+	}
+
+	Plain struct {
+		UserName string %sjson:"user_name"%s
+	}
+)
+`, "%s", "`", -1))
+		})
+
+		Convey("A malformed directive is an error naming the file and line", func() {
+			for directive, reason := range map[string]string{
+				"//@st -case=upper":      "Unknown case provided: upper",
+				"//@st -c":               "flag provided but not defined: -c",
+				"//@st -t=yaml ./...":    "unexpected argument ./...",
+				"//@st -template='gorm:": "unterminated quote",
+				"//@st -a -o":            "only one of -a, -o and -m may be given",
+			} {
+				bad := strings.Replace(src, "//@st -i=Password", directive, 1)
+				_, err := NewTagger(nil, Filters{}).ProcessBytes([]byte(bad), "test.go")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, sterrors.ErrInvalidDirective("test.go:12:2", directive, reason).Error())
+			}
+		})
+	})
+}
+
+func TestFieldDirectives(t *testing.T) {
+	Convey("We can parse the directives in the comments of a field", t, func() {
+		f := fieldOf("*string", false)
+		f.Doc = &ast.CommentGroup{List: []*ast.Comment{{Text: "// ID used by old clients"}, {Text: "// st:name=legacy_id"}}}
+		f.Comment = &ast.CommentGroup{List: []*ast.Comment{{Text: "// st:omitempty st:string st:omitempty"}}}
+		d, _, err := ParseFieldDirectives(f)
+		So(err, ShouldBeNil)
+		So(d, ShouldResemble, FieldDirective{Name: "legacy_id", Options: []string{"omitempty", "string"}})
+
+		f.Comment.List[0].Text = "//st:skip"
+		d, _, err = ParseFieldDirectives(f)
+		So(err, ShouldBeNil)
+		So(d.Skip, ShouldBeTrue)
+
+		for _, text := range []string{"// st:nmae=id", "// st:name=", "// st:name=a,b"} {
+			f.Comment.List[0].Text = text
+			_, c, err := ParseFieldDirectives(f)
+			So(err, ShouldNotBeNil)
+			So(c, ShouldEqual, f.Comment.List[0])
+		}
+
+		f.Doc.List[1].Text = "// listens on st:8080 or st:name=x, given as host:port"
+		f.Comment.List[0].Text = "/* st:omitempty */"
+		d, _, err = ParseFieldDirectives(f)
+		So(err, ShouldBeNil)
+		So(d, ShouldResemble, FieldDirective{})
+
+		d, _, err = ParseFieldDirectives(nil)
+		So(err, ShouldBeNil)
+		So(d, ShouldResemble, FieldDirective{})
+	})
+
+	Convey("Given a struct with field directives", t, func() {
+		opts := DefaultOptions()
+		opts.Tags = []string{"json", "yaml"}
+		src := `package test
+
+type User struct {
+	// st:skip
+	Internal string
+	ID       int    // st:name=legacy_id
+	Nickname string // st:omitempty
+	Password string // st:name=pass
+}
+`
+		Convey("Skipped fields are left alone, and names and options are used for every key", func() {
+			data, err := NewTagger(opts, Filters{IgnoredFields: []string{"Password"}}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type User struct {
+	// st:skip
+	Internal string
+	ID       int    %sjson:"legacy_id" yaml:"legacy_id"%s                   // st:name=legacy_id
+	Nickname string %sjson:"nickname,omitempty" yaml:"nickname,omitempty"%s // st:omitempty
+	Password string %sjson:"pass" yaml:"pass"%s                             // st:name=pass
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Templates are given the name from st:name", func() {
+			tt, err := ParseTagTemplate(`gorm:"column:{{.Snake}}"`)
+			So(err, ShouldBeNil)
+			opts.Templates = []TagTemplate{tt}
+			opts.Tags = []string{"gorm"}
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "ID       int    `gorm:\"column:legacy_id\"`")
+		})
+
+		Convey("Skipped fields are not untagged", func() {
+			opts.Operation = RemoveTags
+			tagged := strings.Replace(src, "Internal string", "Internal string `json:\"internal\"`", 1)
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(tagged), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "Internal string `json:\"internal\"`")
+		})
+
+		Convey("A malformed directive is an error naming the file and line", func() {
+			bad := strings.Replace(src, "st:omitempty", "st:omitepmty", 1)
+			_, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(bad), "test.go")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrInvalidDirective("test.go:7:18", "// st:omitepmty", "unknown field directive st:omitepmty").Error())
+
+			bad = strings.Replace(src, "ID       int", "ID, Key int", 1)
+			_, err = NewTagger(opts, Filters{}).ProcessBytes([]byte(bad), "test.go")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "test.go:6:2: Invalid directive st:name=legacy_id")
+		})
+
+		Convey("st: in the middle of any other comment is left alone", func() {
+			prose := "package test\n\ntype Server struct {\n\tAddr string // e.g. st:8080 or host:port\n}\n"
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(prose), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "Addr string `json:\"addr\" yaml:\"addr\"` // e.g. st:8080 or host:port")
+		})
+	})
+}
+
+func TestStdin(t *testing.T) {
+	Convey("Given source on stdin", t, func() {
+		// process gives src on stdin to the tagger and returns what it writes to stdout. The standard streams are only
+		// replaced while tagging, since goconvey reports its progress on stdout.
+		process := func(tagger *Tagger, src string) (string, error) {
+			in, err := ioutil.TempFile(tempDir, "")
+			So(err, ShouldBeNil)
+			defer in.Close()
+			out, err := ioutil.TempFile(tempDir, "")
+			So(err, ShouldBeNil)
+			defer out.Close()
+			_, err = in.WriteString(src)
+			So(err, ShouldBeNil)
+			_, err = in.Seek(0, 0)
+			So(err, ShouldBeNil)
+
+			stdin, stdout := os.Stdin, os.Stdout
+			os.Stdin, os.Stdout = in, out
+			err = tagger.AndProcessFiles([]string{StdinPath})
+			os.Stdin, os.Stdout = stdin, stdout
+
+			data, readErr := ioutil.ReadFile(out.Name())
+			So(readErr, ShouldBeNil)
+			return string(data), err
+		}
+
+		Convey("The tagged source is written to stdout exactly as it is", func() {
+			data, err := process(NewTagger(nil, Filters{}), testDataNoExistingTags)
+			So(err, ShouldBeNil)
+			So(data, ShouldEqual, snakeTestDataExistingTags)
+		})
+
+		Convey("Errors and directives use the filename", func() {
+			opts := DefaultOptions()
+			opts.Filename = "models/user.go"
+			_, err := process(NewTagger(opts, Filters{}), "package test\n\n//@st -case=upper\ntype User struct {\n\tName string\n}\n")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "models/user.go:3:1: Invalid directive")
+
+			_, err = process(NewTagger(nil, Filters{}), "package test\n\ntype {")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, StdinFilename+":3:")
+		})
+
+		Convey("Check mode prints the filename when the source is not tagged", func() {
+			opts := DefaultOptions()
+			opts.Check = true
+			data, err := process(NewTagger(opts, Filters{}), testDataNoExistingTags)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrFilesNotTagged(1).Error())
+			So(data, ShouldEqual, StdinFilename+"\n")
+		})
 	})
 }
 
@@ -374,10 +1329,9 @@ asofinqowkernoaskn{{}}'`
 			So(err, ShouldNotBeNil)
 		})
 
-		Convey("Passing a directory returns an error", func() {
-			err := AndProcessFiles([]string{tempDir})
+		Convey("Passing a pattern that is not valid returns an error", func() {
+			err := AndProcessFiles([]string{filepath.Join(tempDir, "[")})
 			So(err, ShouldNotBeNil)
-			So(err.Error(), ShouldContainSubstring, fmt.Sprintf("Cannot use a directory as a path."))
 		})
 	})
 
@@ -406,6 +1360,120 @@ type TestStruct struct {
 	})
 }
 
+func TestFileErrors(t *testing.T) {
+	Convey("Given a directory with a good file and a file with a syntax error", t, func() {
+		dir, err := ioutil.TempDir(tempDir, "errors")
+		So(err, ShouldBeNil)
+		bad := filepath.Join(dir, "a_bad.go")
+		good := filepath.Join(dir, "b_good.go")
+		badSrc := "package test\n\ntype TestStruct struct {\n\tField string\n"
+		err = ioutil.WriteFile(bad, []byte(badSrc), 0664)
+		So(err, ShouldBeNil)
+		err = ioutil.WriteFile(good, []byte(testDataNoExistingTags), 0664)
+		So(err, ShouldBeNil)
+		opts := DefaultOptions()
+		opts.DryRun = false
+		tagger := NewTagger(opts, Filters{})
+
+		Convey("By default we stop at the first error, which names the file, line and column", func() {
+			err := tagger.AndProcessFiles([]string{dir})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, bad+":4:15: ")
+			data, err := ioutil.ReadFile(good)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, testDataNoExistingTags)
+		})
+
+		Convey("When continuing on errors every file is processed and the errors are collected", func() {
+			opts.ContinueOnError = true
+			err := tagger.AndProcessFiles([]string{dir})
+			So(err, ShouldNotBeNil)
+			multi, ok := err.(*sterrors.MultiError)
+			So(ok, ShouldBeTrue)
+			So(len(multi.Errors), ShouldEqual, 1)
+			So(multi.Total, ShouldEqual, 2)
+			So(err.Error(), ShouldContainSubstring, bad+":4:15: ")
+			So(err.Error(), ShouldEndWith, "1 of 2 files failed")
+			data, err := ioutil.ReadFile(good)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, snakeTestDataExistingTags)
+		})
+
+		Convey("A file that fails is never written", func() {
+			opts.ContinueOnError = true
+			tagger.AndProcessFiles([]string{dir})
+			data, err := ioutil.ReadFile(bad)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, badSrc)
+		})
+
+		Reset(func() {
+			err = os.RemoveAll(dir)
+			So(err, ShouldBeNil)
+		})
+	})
+
+	Convey("Errors without filenames are given the filename", t, func() {
+		var list scanner.ErrorList
+		list.Add(token.Position{Line: 1, Column: 2}, "an error")
+		err := withFilename(list, "test.go")
+		So(err.Error(), ShouldEqual, "test.go:1:2: an error")
+	})
+}
+
+func TestCheck(t *testing.T) {
+	Convey("Given a directory with a tagged file and an untagged file", t, func() {
+		dir, err := ioutil.TempDir(tempDir, "check")
+		So(err, ShouldBeNil)
+		tagged := filepath.Join(dir, "tagged.go")
+		untagged := filepath.Join(dir, "untagged.go")
+		err = ioutil.WriteFile(tagged, []byte(snakeTestDataExistingTags), 0664)
+		So(err, ShouldBeNil)
+		err = ioutil.WriteFile(untagged, []byte(testDataNoExistingTags), 0664)
+		So(err, ShouldBeNil)
+		opts := DefaultOptions()
+		opts.Check = true
+		opts.DryRun = false
+
+		Convey("Only the untagged file is listed, an error is returned, and nothing is written", func() {
+			stdout := os.Stdout
+			fname := filepath.Join(dir, "stdout")
+			temp, err := os.Create(fname)
+			So(err, ShouldBeNil)
+			os.Stdout = temp
+			checkErr := NewTagger(opts, Filters{}).AndProcessFiles([]string{dir})
+			os.Stdout = stdout
+			err = temp.Close()
+			So(err, ShouldBeNil)
+
+			So(checkErr, ShouldNotBeNil)
+			So(checkErr.Error(), ShouldEqual, sterrors.ErrFilesNotTagged(1).Error())
+			output, err := ioutil.ReadFile(fname)
+			So(err, ShouldBeNil)
+			So(string(output), ShouldEqual, untagged+"\n")
+			data, err := ioutil.ReadFile(untagged)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, testDataNoExistingTags)
+		})
+
+		Convey("Files that are tagged but not formatted are not listed", func() {
+			err := NewTagger(opts, Filters{}).AndProcessFiles([]string{tagged})
+			So(err, ShouldBeNil)
+			So(WouldChange([]byte("package test\ntype T struct {\n\tF string `json:\"f\"`\n}\n"), []byte(strings.Replace(`package test
+
+type T struct {
+	F string %sjson:"f"%s
+}
+`, "%s", "`", -1))), ShouldBeFalse)
+		})
+
+		Reset(func() {
+			err = os.RemoveAll(dir)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
 // Below taken from https://github.com/etgryphon/stringUp/blob/master/stringUp_test.go
 func TestCamelCased(t *testing.T) {
 	const cameled, upCameled = "thisIsIt", "ThisIsItBob"
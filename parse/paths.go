@@ -0,0 +1,123 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RecursiveSuffix is the suffix that marks a path as recursive, as in ./...
+const RecursiveSuffix = "..."
+
+// ExpandPaths expands a list of paths into a list of .go files. Paths ending in ... (such as ./...) are walked
+// recursively, directories are searched for .go files without recursing, and glob patterns are matched with
+// filepath.Glob. vendor, testdata, and hidden directories are skipped while walking or matching, as are _test.go files
+// unless includeTests is true. Files that are given explicitly are always included.
+func ExpandPaths(paths []string, includeTests bool) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(names ...string) {
+		for _, n := range names {
+			if !seen[n] {
+				seen[n] = true
+				files = append(files, n)
+			}
+		}
+	}
+
+	for _, p := range paths {
+		if strings.HasSuffix(p, RecursiveSuffix) {
+			root := filepath.Clean(strings.TrimSuffix(p, RecursiveSuffix))
+			found, err := walkDir(root, includeTests)
+			if err != nil {
+				return nil, err
+			}
+			add(found...)
+			continue
+		}
+
+		matches := []string{p}
+		if strings.ContainsAny(p, "*?[") {
+			var err error
+			matches, err = filepath.Glob(p)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, m := range matches {
+			fi, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if fi.IsDir() {
+				if m != p && isSkippedDir(fi.Name()) {
+					continue
+				}
+				found, err := goFilesInDir(m, includeTests)
+				if err != nil {
+					return nil, err
+				}
+				add(found...)
+			} else if m == p || isGoFile(fi.Name(), includeTests) {
+				add(m)
+			}
+		}
+	}
+	return files, nil
+}
+
+// walkDir walks root recursively and returns every .go file found, skipping directories that should not be walked
+func walkDir(root string, includeTests bool) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if path != root && isSkippedDir(fi.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isGoFile(fi.Name(), includeTests) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// goFilesInDir returns every .go file in dir without recursing into subdirectories
+func goFilesInDir(dir string, includeTests bool) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, fi := range infos {
+		if !fi.IsDir() && isGoFile(fi.Name(), includeTests) {
+			files = append(files, filepath.Join(dir, fi.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isSkippedDir checks if a directory should be skipped when walking recursively
+func isSkippedDir(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// isGoFile checks if name is a .go source file that should be processed
+func isGoFile(name string, includeTests bool) bool {
+	if !strings.HasSuffix(name, ".go") || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return false
+	}
+	return includeTests || !strings.HasSuffix(name, "_test.go")
+}
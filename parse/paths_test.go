@@ -0,0 +1,80 @@
+package parse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExpandPaths(t *testing.T) {
+	Convey("Given a directory tree with go files", t, func() {
+		root, err := ioutil.TempDir(tempDir, "paths")
+		So(err, ShouldBeNil)
+		for _, name := range []string{
+			"a.go",
+			"a_test.go",
+			"notes.txt",
+			"models/b.go",
+			"models/nested/c.go",
+			"vendor/v.go",
+			"testdata/t.go",
+			".hidden/h.go",
+		} {
+			path := filepath.Join(root, filepath.FromSlash(name))
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			So(err, ShouldBeNil)
+			err = ioutil.WriteFile(path, []byte("package test\n"), 0664)
+			So(err, ShouldBeNil)
+		}
+		join := func(names ...string) []string {
+			var paths []string
+			for _, n := range names {
+				paths = append(paths, filepath.Join(root, filepath.FromSlash(n)))
+			}
+			return paths
+		}
+
+		Convey("A directory expands to the go files in it without recursing", func() {
+			files, err := ExpandPaths([]string{root}, false)
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, join("a.go"))
+		})
+
+		Convey("A directory ending in ... is walked recursively, skipping vendor, testdata and hidden directories", func() {
+			files, err := ExpandPaths([]string{filepath.Join(root, RecursiveSuffix)}, false)
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, join("a.go", "models/b.go", "models/nested/c.go"))
+		})
+
+		Convey("Test files are included when asked for", func() {
+			files, err := ExpandPaths([]string{filepath.Join(root, RecursiveSuffix)}, true)
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, join("a.go", "a_test.go", "models/b.go", "models/nested/c.go"))
+		})
+
+		Convey("A glob pattern is matched against go files", func() {
+			files, err := ExpandPaths([]string{filepath.Join(root, "*")}, false)
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, join("a.go", "models/b.go"))
+		})
+
+		Convey("An explicit file is always included, and files are only listed once", func() {
+			files, err := ExpandPaths(join("a_test.go", "notes.txt", "a_test.go"), false)
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, join("a_test.go", "notes.txt"))
+		})
+
+		Convey("A path that does not exist returns an error", func() {
+			_, err := ExpandPaths(join("missing"), false)
+			So(err, ShouldNotBeNil)
+		})
+
+		Reset(func() {
+			err = os.RemoveAll(root)
+			So(err, ShouldBeNil)
+		})
+	})
+}
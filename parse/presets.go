@@ -0,0 +1,116 @@
+package parse
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alistanis/st/sterrors"
+)
+
+// DefaultPresets contains the presets that can be given by name with -preset. Each one holds the settings that a
+// library expects, in the same form as a config file, and presets given together are combined.
+var DefaultPresets = map[string]*Config{
+	// encoding/json
+	"json": {Tags: []string{"json=snake"}},
+	// gorm reads column names from gorm:"column:name"
+	"gorm": {Templates: []string{`gorm:"column:{{.Snake}}"`}},
+	// sqlx and other database/sql scanners read db tags
+	"db":   {Tags: []string{"db=snake"}},
+	"sqlx": {Tags: []string{"db=snake"}},
+	// the mongo driver reads bson tags, and inlines embedded structs with bson:",inline"
+	"bson":  {Tags: []string{"bson=camel"}, Opts: []string{"omitempty"}, Embedded: "inline"},
+	"mongo": {Tags: []string{"bson=camel"}, Opts: []string{"omitempty"}, Embedded: "inline"},
+	// gopkg.in/yaml inlines embedded structs with yaml:",inline"
+	"yaml": {Tags: []string{"yaml=camel"}, Opts: []string{"omitempty"}, Embedded: "inline"},
+	"toml": {Tags: []string{"toml=snake"}},
+	// mapstructure inlines embedded structs with mapstructure:",squash"
+	"mapstructure": {Tags: []string{"mapstructure=snake"}, Embedded: "inline"},
+	// envconfig reads environment variable names such as DATABASE_URL
+	"envconfig": {Tags: []string{"envconfig=screaming"}},
+	// go-playground/validator: fields that can be nil are optional, and every other field is required
+	"validator": {Templates: []string{`validate:"{{if or .IsPointer .IsSlice .IsMap}}omitempty{{else}}required{{end}}"`}},
+}
+
+// PresetNames returns the names of the DefaultPresets, sorted
+func PresetNames() []string {
+	var names []string
+	for name := range DefaultPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FindPreset returns the preset with the given name, which is one of the config's Presets or one of the DefaultPresets,
+// and false if there is none
+func (c *Config) FindPreset(name string) (*Config, bool) {
+	if p, ok := c.Presets[name]; ok && p != nil {
+		return p, true
+	}
+	p, ok := DefaultPresets[name]
+	return p, ok
+}
+
+// CombinePresets combines the named presets into one, in order. Tags, options, initialisms and templates are combined,
+// and the case and the embedded and multi-name field policies of later presets are used over those of earlier ones.
+// The options of a preset with tags are only given for its own tag keys, as in bson:omitempty, so that they are not
+// added to the keys of the other presets.
+func (c *Config) CombinePresets(names ...string) (*Config, error) {
+	combined := &Config{}
+	for _, name := range names {
+		p, ok := c.FindPreset(name)
+		if !ok {
+			return nil, sterrors.ErrUnknownPreset(name)
+		}
+		combined.Tags = appendMissing(combined.Tags, p.Tags...)
+		combined.Opts = appendMissing(combined.Opts, p.keyOpts()...)
+		combined.Initialisms = appendMissing(combined.Initialisms, p.Initialisms...)
+		combined.Templates = appendMissing(combined.Templates, p.Templates...)
+		combined.IgnoredFields = appendMissing(combined.IgnoredFields, p.IgnoredFields...)
+		combined.IgnoredStructs = appendMissing(combined.IgnoredStructs, p.IgnoredStructs...)
+		if p.Case != "" {
+			combined.Case = p.Case
+		}
+		if p.Embedded != "" {
+			combined.Embedded = p.Embedded
+		}
+		if p.Multi != "" {
+			combined.Multi = p.Multi
+		}
+	}
+	return combined, nil
+}
+
+// keyOpts returns the options of the preset given for each of its tag keys, or as they are if it has no tags or they
+// are already given for one key
+func (c *Config) keyOpts() []string {
+	tags, _, err := parseTags(strings.Join(c.Tags, ","))
+	if err != nil {
+		return c.Opts
+	}
+	var opts []string
+	for _, opt := range c.Opts {
+		if strings.Contains(opt, ":") {
+			opts = append(opts, opt)
+			continue
+		}
+		for _, key := range tags {
+			r, ok := FindOptionRule(opt)
+			if ok && len(r.Keys) > 0 && !contains(r.Keys, key) {
+				continue
+			}
+			opts = append(opts, key+":"+opt)
+		}
+	}
+	return opts
+}
+
+// appendMissing appends every value that is not already in list
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		if !contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
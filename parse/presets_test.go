@@ -0,0 +1,123 @@
+package parse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alistanis/st/sterrors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPresets(t *testing.T) {
+	Convey("Every default preset is valid", t, func() {
+		c := &Config{}
+		for _, name := range PresetNames() {
+			p, err := c.CombinePresets(name)
+			So(err, ShouldBeNil)
+			So(len(p.Tags)+len(p.Templates), ShouldBeGreaterThan, 0)
+			if len(p.Tags) > 0 {
+				_, _, err = parseTags(strings.Join(p.Tags, ","))
+				So(err, ShouldBeNil)
+			}
+			_, err = parseTemplates(p.Templates)
+			So(err, ShouldBeNil)
+			_, err = parseOptionRules(strings.Join(p.Opts, ","))
+			So(err, ShouldBeNil)
+			if p.Embedded != "" {
+				_, ok := EmbeddedPolicies[p.Embedded]
+				So(ok, ShouldBeTrue)
+			}
+		}
+	})
+
+	Convey("Presets are combined in order", t, func() {
+		c := &Config{Presets: map[string]*Config{"mongo": {Tags: []string{"bson=snake"}, Embedded: "name"}}}
+		p, err := c.CombinePresets("json", "gorm", "mongo", "yaml")
+		So(err, ShouldBeNil)
+		So(p.Tags, ShouldResemble, []string{"json=snake", "bson=snake", "yaml=camel"})
+		So(p.Templates, ShouldResemble, []string{`gorm:"column:{{.Snake}}"`})
+		So(p.Opts, ShouldResemble, []string{"yaml:omitempty"})
+		So(p.Embedded, ShouldEqual, "inline")
+
+		p, err = c.CombinePresets("json", "strict")
+		So(err, ShouldNotBeNil)
+		c.Presets["strict"] = &Config{Tags: []string{"json", "xml=camel"}, Opts: []string{"omitempty", "string", "db:omitempty"}}
+		p, err = c.CombinePresets("mongo", "strict")
+		So(err, ShouldBeNil)
+		So(p.Opts, ShouldResemble, []string{"json:omitempty", "xml:omitempty", "json:string", "db:omitempty"})
+
+		_, err = c.CombinePresets("json", "django")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, sterrors.ErrUnknownPreset("django").Error())
+	})
+
+	Convey("Given presets on the command line", t, func() {
+		root, err := ioutil.TempDir(tempDir, "presets")
+		So(err, ShouldBeNil)
+
+		Convey("They are used for the flags that are not given", func() {
+			SetArgs([]string{"-preset=json,mongo", "-opts=string", root})
+			err := Flags()
+			So(err, ShouldBeNil)
+			tagger := flagTagger(root)
+			So(tagger.Options.Tags, ShouldResemble, []string{"json", "bson"})
+			So(tagger.Options.Cases, ShouldResemble, map[string]string{"json": Snake, "bson": Camel})
+			So(tagger.Options.Embedded, ShouldEqual, EmbeddedInline)
+			So(len(tagger.Options.OptionRules), ShouldEqual, 1)
+			So(tagger.Options.OptionRules[0].Name, ShouldEqual, "string")
+
+			SetArgs([]string{"-preset=json,mongo", root})
+			err = Flags()
+			So(err, ShouldBeNil)
+			tagger = flagTagger(root)
+			src := "package test\n\ntype Post struct {\n\tTags []string\n}\n"
+			data, err := tagger.ProcessBytes([]byte(src), "post.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "`json:\"tags\" bson:\"tags,omitempty\"`")
+
+			SetArgs([]string{"-preset=django", root})
+			err = Flags()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrUnknownPreset("django").Error())
+		})
+
+		Convey("They are used under go generate", func() {
+			os.Setenv("GOFILE", filepath.Join(root, "user.go"))
+			Reset(func() {
+				os.Setenv("GOFILE", "")
+			})
+			SetArgs([]string{"-preset=yaml"})
+			err := Flags()
+			So(err, ShouldBeNil)
+			So(flagTagger(root).Options.Tags, ShouldResemble, []string{"yaml"})
+		})
+
+		Convey("Settings in the config file are used over them, and the config file can give and define presets", func() {
+			err := ioutil.WriteFile(filepath.Join(root, ConfigFileName), []byte(`{
+	"preset": ["db", "strict"],
+	"embedded": "skip",
+	"presets": {"strict": {"opts": ["omitempty"], "multi": "split"}}
+}`), 0664)
+			So(err, ShouldBeNil)
+			SetArgs([]string{root})
+			err = Flags()
+			So(err, ShouldBeNil)
+			tagger := flagTagger(root)
+			So(tagger.Options.Tags, ShouldResemble, []string{"db"})
+			So(tagger.Options.Embedded, ShouldEqual, EmbeddedSkip)
+			So(tagger.Options.MultiNames, ShouldEqual, MultiSplit)
+			So(len(tagger.Options.OptionRules), ShouldEqual, 1)
+
+			SetArgs([]string{"-preset=yaml", root})
+			err = Flags()
+			So(err, ShouldBeNil)
+			tagger = flagTagger(root)
+			So(tagger.Options.Tags, ShouldResemble, []string{"yaml"})
+			So(tagger.Options.Embedded, ShouldEqual, EmbeddedSkip)
+			So(tagger.Options.MultiNames, ShouldEqual, MultiReport)
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package parse
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// OptionRule adds Option (such as omitempty) to the tag of every field that Match returns true for. If Keys is not
+// empty, the option is only added for those tag keys, since some options only mean something to one encoder. When the
+// type of the field is known (with -types), MatchType is given the type in place of calling Match, if it is set.
+type OptionRule struct {
+	Name      string
+	Option    string
+	Keys      []string
+	Match     func(f *ast.Field) bool
+	MatchType func(t types.Type) bool
+}
+
+// DefaultOptionRules contains the option rules that can be given by name on the command line
+var DefaultOptionRules = []OptionRule{
+	// omitempty for fields that can be nil
+	{Name: "omitempty", Option: "omitempty", Match: IsNillable, MatchType: IsNillableType},
+	// string for 64 bit integers, which JavaScript cannot represent exactly as numbers
+	{Name: "string", Option: "string", Keys: []string{JSON}, Match: Is64BitInteger, MatchType: Is64BitIntegerType},
+	// inline for embedded fields, for the encoders that support it. Embedded fields are tagged with only this option,
+	// as in yaml:",inline", even when the embedded field policy would skip them.
+	{Name: InlineOption, Option: InlineOption, Keys: []string{"yaml", "bson"}, Match: IsEmbedded},
+}
+
+// InlineOption is the option that inlines an embedded field for yaml and bson, and the name of the rule that adds it
+const InlineOption = "inline"
+
+// FindOptionRule returns the rule in DefaultOptionRules with the given name, and false if there is none
+func FindOptionRule(name string) (OptionRule, bool) {
+	for _, r := range DefaultOptionRules {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return OptionRule{}, false
+}
+
+// AppliesTo checks if the rule adds its option to the tag key for the field f, whose type is typ, or nil if it is not
+// known
+func (r OptionRule) AppliesTo(key string, f *ast.Field, typ types.Type) bool {
+	if len(r.Keys) > 0 && !contains(r.Keys, key) {
+		return false
+	}
+	if typ != nil && r.MatchType != nil {
+		return r.MatchType(typ)
+	}
+	return r.Match != nil && r.Match(f)
+}
+
+// inlinesEmbedded checks if the inline rule is one of the Tagger's OptionRules
+func (t *Tagger) inlinesEmbedded() bool {
+	for _, r := range t.Options.OptionRules {
+		if r.Name == InlineOption {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNillable checks if the type of the field is a pointer, slice, map, or interface
+func IsNillable(f *ast.Field) bool {
+	switch t := f.Type.(type) {
+	case *ast.StarExpr, *ast.MapType, *ast.InterfaceType:
+		return true
+	case *ast.ArrayType:
+		// arrays have a length, slices do not
+		return t.Len == nil
+	}
+	return false
+}
+
+// Is64BitInteger checks if the type of the field is int64 or uint64
+func Is64BitInteger(f *ast.Field) bool {
+	ident, ok := f.Type.(*ast.Ident)
+	return ok && (ident.Name == "int64" || ident.Name == "uint64")
+}
+
+// IsEmbedded checks if the field is an embedded (anonymous) field
+func IsEmbedded(f *ast.Field) bool {
+	return len(f.Names) == 0
+}
+
+// FieldOptions returns the options that the Tagger's OptionRules add to the tag key for the field f, in the order of
+// the rules
+func (t *Tagger) FieldOptions(key string, f *ast.Field) []string {
+	if f == nil {
+		return nil
+	}
+	typ := t.typeOf(f.Type)
+	var opts []string
+	for _, r := range t.Options.OptionRules {
+		if r.AppliesTo(key, f, typ) && !contains(opts, r.Option) {
+			opts = append(opts, r.Option)
+		}
+	}
+	return opts
+}
@@ -0,0 +1,107 @@
+package parse
+
+import (
+	"go/ast"
+	"go/parser"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fieldOf parses the type expression and returns a field with that type, with no names if embedded is true
+func fieldOf(expr string, embedded bool) *ast.Field {
+	e, err := parser.ParseExpr(expr)
+	So(err, ShouldBeNil)
+	f := &ast.Field{Type: e}
+	if !embedded {
+		f.Names = []*ast.Ident{ast.NewIdent("Field")}
+	}
+	return f
+}
+
+func TestOptionRules(t *testing.T) {
+	Convey("Given field types", t, func() {
+		Convey("Pointers, slices, maps and interfaces are nillable", func() {
+			for _, e := range []string{"*int", "[]string", "map[string]int", "interface{}", "[]*pkg.Type"} {
+				So(IsNillable(fieldOf(e, false)), ShouldBeTrue)
+			}
+			for _, e := range []string{"int", "[4]byte", "pkg.Type", "struct{}"} {
+				So(IsNillable(fieldOf(e, false)), ShouldBeFalse)
+			}
+		})
+
+		Convey("Only int64 and uint64 are 64 bit integers", func() {
+			So(Is64BitInteger(fieldOf("int64", false)), ShouldBeTrue)
+			So(Is64BitInteger(fieldOf("uint64", false)), ShouldBeTrue)
+			So(Is64BitInteger(fieldOf("int", false)), ShouldBeFalse)
+			So(Is64BitInteger(fieldOf("*int64", false)), ShouldBeFalse)
+		})
+
+		Convey("Fields without names are embedded", func() {
+			So(IsEmbedded(fieldOf("Base", true)), ShouldBeTrue)
+			So(IsEmbedded(fieldOf("Base", false)), ShouldBeFalse)
+		})
+	})
+
+	Convey("We can find the default rules by name", t, func() {
+		r, ok := FindOptionRule("string")
+		So(ok, ShouldBeTrue)
+		So(r.Option, ShouldEqual, "string")
+		So(r.AppliesTo(JSON, fieldOf("int64", false), nil), ShouldBeTrue)
+		So(r.AppliesTo("yaml", fieldOf("int64", false), nil), ShouldBeFalse)
+
+		_, ok = FindOptionRule("required")
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Given a struct and option rules", t, func() {
+		opts := DefaultOptions()
+		opts.Tags = []string{"json", "yaml"}
+		for _, name := range []string{"omitempty", "string"} {
+			r, _ := FindOptionRule(name)
+			opts.OptionRules = append(opts.OptionRules, r)
+		}
+		src := strings.Replace(`package test
+
+type TestStruct struct {
+	ID      int64
+	Name    string
+	Tags    []string
+	Parent  *TestStruct
+	Counter uint64 %sjson:"counter"%s
+}
+`, "%s", "`", -1)
+
+		Convey("The options are added to the tags that are written", func() {
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type TestStruct struct {
+	ID      int64       %sjson:"id,string" yaml:"id"%s
+	Name    string      %sjson:"name" yaml:"name"%s
+	Tags    []string    %sjson:"tags,omitempty" yaml:"tags,omitempty"%s
+	Parent  *TestStruct %sjson:"parent,omitempty" yaml:"parent,omitempty"%s
+	Counter uint64      %sjson:"counter" yaml:"counter"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Merge mode adds missing options to existing keys without repeating them", func() {
+			opts.AppendMode = Update
+			opts.Tags = []string{"json"}
+			src := strings.Replace(`package test
+
+type TestStruct struct {
+	Tags []string %sjson:"Tags,omitempty"%s
+	ID   int64    %sjson:"ID,omitempty"%s
+}
+`, "%s", "`", -1)
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "`json:\"tags,omitempty\"`")
+			So(string(data), ShouldContainSubstring, "`json:\"id,omitempty,string\"`")
+		})
+	})
+}
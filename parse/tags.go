@@ -0,0 +1,127 @@
+package parse
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alistanis/st/sterrors"
+)
+
+// TagPair is a single key:"value" pair from a struct tag
+type TagPair struct {
+	Key   string
+	Value string
+}
+
+// Name returns the part of the value before the first comma, which is the name for most tags (json, yaml, xml, etc)
+func (p TagPair) Name() string {
+	if i := strings.Index(p.Value, ","); i != -1 {
+		return p.Value[:i]
+	}
+	return p.Value
+}
+
+// Options returns the part of the value from the first comma on, such as ",omitempty", or an empty string
+func (p TagPair) Options() string {
+	if i := strings.Index(p.Value, ","); i != -1 {
+		return p.Value[i:]
+	}
+	return ""
+}
+
+// WithOptions returns a copy of the pair with every option in opts that it does not already have added to the end of its
+// value
+func (p TagPair) WithOptions(opts ...string) TagPair {
+	have := strings.Split(p.Options(), ",")
+	for _, o := range opts {
+		if !contains(have, o) {
+			p.Value += "," + o
+			have = append(have, o)
+		}
+	}
+	return p
+}
+
+// String returns the pair in the format key:"value"
+func (p TagPair) String() string {
+	return p.Key + ":" + strconv.Quote(p.Value)
+}
+
+// KeyMapping maps the tag key From to the tag key To, such as db to sql when renaming keys
+type KeyMapping struct {
+	From string
+	To   string
+}
+
+// StructTag is the ordered list of key:"value" pairs in a struct tag
+type StructTag []TagPair
+
+// ParseStructTag parses the contents of a struct tag (without backticks) into its pairs, keeping their order. It
+// follows the same conventions as reflect.StructTag, but returns an error if the tag is malformed rather than ignoring
+// the rest of it.
+func ParseStructTag(tag string) (StructTag, error) {
+	var pairs StructTag
+	s := tag
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return pairs, nil
+		}
+
+		// a key is a non-empty string of characters other than space, quote, and colon
+		i := 0
+		for i < len(s) && s[i] > ' ' && s[i] != ':' && s[i] != '"' && s[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(s) || s[i] != ':' || s[i+1] != '"' {
+			return nil, sterrors.ErrMalformedTag(tag)
+		}
+		key := s[:i]
+		s = s[i+1:]
+
+		// the value is a quoted string, which may contain escaped quotes
+		i = 1
+		for i < len(s) && s[i] != '"' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(s) {
+			return nil, sterrors.ErrMalformedTag(tag)
+		}
+		value, err := strconv.Unquote(s[:i+1])
+		if err != nil {
+			return nil, sterrors.ErrMalformedTag(tag)
+		}
+		s = s[i+1:]
+		pairs = append(pairs, TagPair{Key: key, Value: value})
+	}
+}
+
+// Index returns the index of the pair with the given key, or -1 if there is none
+func (t StructTag) Index(key string) int {
+	for i, p := range t {
+		if p.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the value for the given key, or an empty string if there is none
+func (t StructTag) Get(key string) string {
+	if i := t.Index(key); i != -1 {
+		return t[i].Value
+	}
+	return ""
+}
+
+// String returns the struct tag contents (without backticks), with the pairs separated by spaces
+func (t StructTag) String() string {
+	pairs := make([]string, len(t))
+	for i, p := range t {
+		pairs[i] = p.String()
+	}
+	return strings.Join(pairs, " ")
+}
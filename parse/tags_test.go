@@ -0,0 +1,60 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/alistanis/st/sterrors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseStructTag(t *testing.T) {
+	Convey("Given a struct tag with several keys", t, func() {
+		tag, err := ParseStructTag(`json:"name,omitempty"  db:"column:user_id;not null" xml:"a \"quoted\" name"`)
+		So(err, ShouldBeNil)
+
+		Convey("The pairs are parsed in order", func() {
+			So(tag, ShouldResemble, StructTag{
+				{Key: "json", Value: "name,omitempty"},
+				{Key: "db", Value: "column:user_id;not null"},
+				{Key: "xml", Value: `a "quoted" name`},
+			})
+		})
+
+		Convey("We can split a value into its name and options", func() {
+			So(tag[0].Name(), ShouldEqual, "name")
+			So(tag[0].Options(), ShouldEqual, ",omitempty")
+			So(tag[1].Name(), ShouldEqual, "column:user_id;not null")
+			So(tag[1].Options(), ShouldEqual, "")
+		})
+
+		Convey("We can add options that a pair does not already have", func() {
+			So(tag[0].WithOptions("omitempty", "string").Value, ShouldEqual, "name,omitempty,string")
+			So(tag[1].WithOptions("string").Value, ShouldEqual, "column:user_id;not null,string")
+		})
+
+		Convey("We can look up keys", func() {
+			So(tag.Index("db"), ShouldEqual, 1)
+			So(tag.Index("yaml"), ShouldEqual, -1)
+			So(tag.Get("json"), ShouldEqual, "name,omitempty")
+			So(tag.Get("yaml"), ShouldEqual, "")
+		})
+
+		Convey("We can turn the pairs back into a tag", func() {
+			So(tag.String(), ShouldEqual, `json:"name,omitempty" db:"column:user_id;not null" xml:"a \"quoted\" name"`)
+		})
+	})
+
+	Convey("An empty tag has no pairs", t, func() {
+		tag, err := ParseStructTag("")
+		So(err, ShouldBeNil)
+		So(len(tag), ShouldEqual, 0)
+	})
+
+	Convey("Malformed tags return an error", t, func() {
+		for _, s := range []string{`json`, `json:name`, `json:"name`, `:"name"`, `json:"name" db`} {
+			_, err := ParseStructTag(s)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, sterrors.ErrMalformedTag(s).Error())
+		}
+	})
+}
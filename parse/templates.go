@@ -0,0 +1,141 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/alistanis/st/sterrors"
+)
+
+// TagTemplate writes the value of the tag Key with a text/template, which is executed with a TemplateData for each field
+type TagTemplate struct {
+	Key      string
+	Template *template.Template
+}
+
+// TemplateData is the data that a TagTemplate is executed with. Name is the field name as it is, and Snake through Dot
+// are the field name in each supported case. Struct is the name of the struct, Type is the Go type of the field as it
+// is written in the source (such as *time.Time), and Doc is the field's doc comment, or its line comment if it has no
+// doc comment. When the type of the field is known (with -types), TypeName is the type with packages written by their
+// import path, Underlying is its underlying type, and IsPointer through IsInterface describe the underlying type, so a
+// field of type Labels, declared as type Labels map[string]string, is a map. Otherwise TypeName and Underlying are the
+// same as Type.
+type TemplateData struct {
+	Name        string
+	Snake       string
+	Camel       string
+	Kebab       string
+	Pascal      string
+	Screaming   string
+	Lower       string
+	Dot         string
+	Struct      string
+	Type        string
+	TypeName    string
+	Underlying  string
+	IsPointer   bool
+	IsSlice     bool
+	IsMap       bool
+	IsStruct    bool
+	IsInterface bool
+	Doc         string
+}
+
+// ParseTagTemplate parses a template given as a struct tag with a single key, such as gorm:"column:{{.Snake}}". The
+// template is executed once with an empty TemplateData so that mistakes such as unknown fields are found right away.
+func ParseTagTemplate(s string) (TagTemplate, error) {
+	tag, err := ParseStructTag(s)
+	if err != nil || len(tag) != 1 {
+		return TagTemplate{}, sterrors.ErrInvalidTemplate(s, "must be a single key:\"template\" pair")
+	}
+	tmpl, err := template.New(tag[0].Key).Option("missingkey=error").Parse(tag[0].Value)
+	if err != nil {
+		return TagTemplate{}, sterrors.ErrInvalidTemplate(s, err.Error())
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, TemplateData{}); err != nil {
+		return TagTemplate{}, sterrors.ErrInvalidTemplate(s, err.Error())
+	}
+	return TagTemplate{Key: tag[0].Key, Template: tmpl}, nil
+}
+
+// Execute returns the value of the tag for a field, given its TemplateData
+func (tt TagTemplate) Execute(d TemplateData) (string, error) {
+	var buf bytes.Buffer
+	err := tt.Template.Execute(&buf, d)
+	return buf.String(), err
+}
+
+// NewTemplateData returns the TemplateData for the field fieldName in the struct structName; field may be nil
+func NewTemplateData(structName, fieldName string, field *ast.Field, initialisms ...string) TemplateData {
+	d := TemplateData{
+		Name:      fieldName,
+		Snake:     FormatName(fieldName, Snake, initialisms...),
+		Camel:     FormatName(fieldName, Camel, initialisms...),
+		Kebab:     FormatName(fieldName, Kebab, initialisms...),
+		Pascal:    FormatName(fieldName, Pascal, initialisms...),
+		Screaming: FormatName(fieldName, Screaming, initialisms...),
+		Lower:     FormatName(fieldName, Lower, initialisms...),
+		Dot:       FormatName(fieldName, Dot, initialisms...),
+		Struct:    structName,
+	}
+	if field == nil {
+		return d
+	}
+	d.Type = types.ExprString(field.Type)
+	d.TypeName, d.Underlying = d.Type, d.Type
+	switch t := field.Type.(type) {
+	case *ast.StarExpr:
+		d.IsPointer = true
+	case *ast.ArrayType:
+		d.IsSlice = t.Len == nil
+	case *ast.MapType:
+		d.IsMap = true
+	case *ast.StructType:
+		d.IsStruct = true
+	case *ast.InterfaceType:
+		d.IsInterface = true
+	}
+	d.Doc = strings.TrimSpace(field.Doc.Text())
+	if d.Doc == "" {
+		d.Doc = strings.TrimSpace(field.Comment.Text())
+	}
+	return d
+}
+
+// SetType sets TypeName, Underlying and IsPointer through IsInterface from the resolved type of the field, writing
+// packages with the qualifier q
+func (d *TemplateData) SetType(typ types.Type, q types.Qualifier) {
+	d.TypeName = types.TypeString(typ, q)
+	u := typ.Underlying()
+	d.Underlying = types.TypeString(u, q)
+	_, d.IsPointer = u.(*types.Pointer)
+	_, d.IsSlice = u.(*types.Slice)
+	_, d.IsMap = u.(*types.Map)
+	_, d.IsStruct = u.(*types.Struct)
+	_, d.IsInterface = u.(*types.Interface)
+}
+
+// Template returns the template for the tag key, or nil if there is none
+func (o *Options) Template(key string) *TagTemplate {
+	for i := range o.Templates {
+		if o.Templates[i].Key == key {
+			return &o.Templates[i]
+		}
+	}
+	return nil
+}
+
+// WrittenKeys returns the tag keys that are written when tagging: the TagKeys, followed by the key of every template that
+// is not one of them
+func (o *Options) WrittenKeys() []string {
+	keys := append([]string(nil), o.TagKeys()...)
+	for _, tt := range o.Templates {
+		if !contains(keys, tt.Key) {
+			keys = append(keys, tt.Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,138 @@
+package parse
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alistanis/st/sterrors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTemplates(t *testing.T) {
+	Convey("We can parse tag templates", t, func() {
+		tt, err := ParseTagTemplate(`gorm:"column:{{.Snake}}"`)
+		So(err, ShouldBeNil)
+		So(tt.Key, ShouldEqual, "gorm")
+
+		Convey("Templates must be a single key:\"template\" pair", func() {
+			for _, s := range []string{`gorm`, `gorm:"a" db:"b"`, ``} {
+				_, err := ParseTagTemplate(s)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, sterrors.ErrInvalidTemplate(s, "must be a single key:\"template\" pair").Error())
+			}
+		})
+
+		Convey("Templates that do not parse or use unknown fields are errors", func() {
+			_, err := ParseTagTemplate(`gorm:"column:{{.Snake"`)
+			So(err, ShouldNotBeNil)
+			_, err = ParseTagTemplate(`gorm:"column:{{.Column}}"`)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a field", t, func() {
+		f := fieldOf("*time.Time", false)
+		f.Doc = &ast.CommentGroup{List: []*ast.Comment{{Text: "// CreatedAt is when the user was created"}}}
+
+		Convey("The template data has the name in every case, the struct name and the type", func() {
+			d := NewTemplateData("User", "CreatedAt", f)
+			So(d, ShouldResemble, TemplateData{
+				Name:       "CreatedAt",
+				Snake:      "created_at",
+				Camel:      "createdAt",
+				Kebab:      "created-at",
+				Pascal:     "CreatedAt",
+				Screaming:  "CREATED_AT",
+				Lower:      "createdat",
+				Dot:        "created.at",
+				Struct:     "User",
+				Type:       "*time.Time",
+				TypeName:   "*time.Time",
+				Underlying: "*time.Time",
+				IsPointer:  true,
+				Doc:        "CreatedAt is when the user was created",
+			})
+		})
+
+		Convey("Slices and maps are detected, and line comments are used without a doc comment", func() {
+			f := fieldOf("[]string", false)
+			f.Comment = &ast.CommentGroup{List: []*ast.Comment{{Text: "// the user's roles"}}}
+			d := NewTemplateData("User", "Roles", f)
+			So(d.IsSlice, ShouldBeTrue)
+			So(d.Doc, ShouldEqual, "the user's roles")
+			So(NewTemplateData("User", "Counts", fieldOf("[4]int", false)).IsSlice, ShouldBeFalse)
+			So(NewTemplateData("User", "Meta", fieldOf("map[string]string", false)).IsMap, ShouldBeTrue)
+		})
+
+		Convey("We can execute a template", func() {
+			tt, err := ParseTagTemplate(`gorm:"column:{{.Snake}}{{if .IsPointer}};default:null{{end}}"`)
+			So(err, ShouldBeNil)
+			v, err := tt.Execute(NewTemplateData("User", "CreatedAt", f))
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, "column:created_at;default:null")
+		})
+	})
+
+	Convey("Given a struct and templates", t, func() {
+		opts := DefaultOptions()
+		gorm, err := ParseTagTemplate(`gorm:"column:{{.Snake}}"`)
+		So(err, ShouldBeNil)
+		json, err := ParseTagTemplate(`json:"{{.Struct}}.{{.Snake}}"`)
+		So(err, ShouldBeNil)
+		opts.Templates = []TagTemplate{gorm}
+		src := `package test
+
+type User struct {
+	UserID int
+	Name   string %sgorm:"column:full_name"%s
+}
+`
+
+		Convey("Template keys are written along with the tags", func() {
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(strings.Replace(src, "%s", "`", -1)), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type User struct {
+	UserID int    %sjson:"user_id" gorm:"column:user_id"%s
+	Name   string %sgorm:"column:full_name" json:"name"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Templates replace the value of a tag key that is also in the tags", func() {
+			opts.Templates = []TagTemplate{json}
+			opts.AppendMode = Update
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(strings.Replace(src, "%s", "`", -1)), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package test
+
+type User struct {
+	UserID int    %sjson:"User.user_id"%s
+	Name   string %sgorm:"column:full_name" json:"User.name"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Results with a backtick are written as an interpreted string literal", func() {
+			doc, err := ParseTagTemplate(`doc:"{{.Doc}}"`)
+			So(err, ShouldBeNil)
+			opts.Templates = []TagTemplate{doc}
+			src := "package test\n\ntype User struct {\n\t// the `id` column\n\tID int\n}\n"
+			data, err := NewTagger(opts, Filters{}).ProcessBytes([]byte(src), "test.go")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "\tID int \"json:\\\"id\\\" doc:\\\"the `id` column\\\"\"\n")
+
+			f, err := parser.ParseFile(token.NewFileSet(), "test.go", data, parser.ParseComments)
+			So(err, ShouldBeNil)
+			tag, err := strconv.Unquote(f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType).Fields.List[0].Tag.Value)
+			So(err, ShouldBeNil)
+			So(reflect.StructTag(tag).Get("doc"), ShouldEqual, "the `id` column")
+		})
+	})
+}
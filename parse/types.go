@@ -0,0 +1,206 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// lockedImporter makes an importer safe to use from multiple goroutines at once
+type lockedImporter struct {
+	sync.Mutex
+	importer types.ImporterFrom
+}
+
+// Import imports the package with the given import path
+func (l *lockedImporter) Import(path string) (*types.Package, error) {
+	return l.ImportFrom(path, "", 0)
+}
+
+// ImportFrom imports the package with the given import path, as imported from the directory dir
+func (l *lockedImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.importer.ImportFrom(path, dir, mode)
+}
+
+// typeInfo holds the type information for the package of the file being tagged
+type typeInfo struct {
+	pkg  *types.Package
+	info *types.Info
+}
+
+// typeChecker type checks the packages of the files that are tagged in one run, with a FileSet and a source importer
+// of its own, so that nothing is kept once the run is over and each imported package is only imported once. The files
+// of the last directory that was checked are kept along with the type information of their packages, so a package
+// whose files are tagged one after another, as they are for a directory or ./..., is only parsed and checked once.
+type typeChecker struct {
+	mu       sync.Mutex
+	fset     *token.FileSet
+	importer *lockedImporter
+	dir      string
+	files    map[string]parsedFile
+	infos    map[packageKey]*typeInfo
+}
+
+// parsedFile is a file of the directory being checked, as it was read from disk
+type parsedFile struct {
+	src  []byte
+	file *ast.File
+}
+
+// packageKey names a package in the directory being checked, which is checked with its _test.go files when the file
+// being tagged is one
+type packageKey struct {
+	name  string
+	tests bool
+}
+
+// newTypeChecker returns a new *typeChecker
+func newTypeChecker() *typeChecker {
+	fset := token.NewFileSet()
+	return &typeChecker{
+		fset:     fset,
+		importer: &lockedImporter{importer: importer.ForCompiler(fset, "source", nil).(types.ImporterFrom)}}
+}
+
+// check parses src as the file filename, and type checks it along with the other files of its package in the same
+// directory, importing packages from their source. When src is the file as it is on disk, the file and the type
+// information of its package are shared with the other files of the package. Type errors, such as imports that cannot
+// be found, are not returned: the types of fields that cannot be resolved are left out of the type information, and
+// those fields are tagged by their syntax alone.
+func (c *typeChecker) check(src []byte, filename string) (*ast.File, *typeInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dir, base := filepath.Dir(filename), filepath.Base(filename)
+	tests := strings.HasSuffix(filename, "_test.go")
+	if c.files == nil || c.dir != dir {
+		c.load(dir)
+	}
+
+	if pf, ok := c.files[base]; ok && bytes.Equal(pf.src, src) {
+		key := packageKey{name: pf.file.Name.Name, tests: tests}
+		if ti, ok := c.infos[key]; ok {
+			return pf.file, ti, nil
+		}
+		ti := c.checkFiles(pf.file.Name.Name, c.packageFiles(key, ""))
+		c.infos[key] = ti
+		return pf.file, ti, nil
+	}
+
+	// the source is not the file on disk, as for stdin, so it is checked on its own along with the files beside it
+	target, err := parser.ParseFile(c.fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := append([]*ast.File{target}, c.packageFiles(packageKey{name: target.Name.Name, tests: tests}, base)...)
+	return target, c.checkFiles(target.Name.Name, files), nil
+}
+
+// load parses the .go files in dir that match the build constraints, replacing the files of the last directory
+func (c *typeChecker) load(dir string) {
+	c.dir, c.files, c.infos = dir, make(map[string]parsedFile), make(map[packageKey]*typeInfo)
+	names, err := goFilesInDir(dir, true)
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		base := filepath.Base(name)
+		if ok, err := build.Default.MatchFile(dir, base); err != nil || !ok {
+			continue
+		}
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		if f, err := parser.ParseFile(c.fset, name, src, parser.ParseComments); err == nil {
+			c.files[base] = parsedFile{src: src, file: f}
+		}
+	}
+}
+
+// packageFiles returns the files of the directory that are in the package, leaving out the file named skip, and
+// _test.go files unless the package is checked with them
+func (c *typeChecker) packageFiles(key packageKey, skip string) []*ast.File {
+	var bases []string
+	for base, pf := range c.files {
+		if base != skip && pf.file.Name.Name == key.name && (key.tests || !strings.HasSuffix(base, "_test.go")) {
+			bases = append(bases, base)
+		}
+	}
+	sort.Strings(bases)
+	files := make([]*ast.File, len(bases))
+	for i, base := range bases {
+		files[i] = c.files[base].file
+	}
+	return files
+}
+
+// checkFiles type checks the files of the package name
+func (c *typeChecker) checkFiles(name string, files []*ast.File) *typeInfo {
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := types.Config{Importer: c.importer, Error: func(error) {}}
+	pkg, _ := conf.Check(name, c.fset, files, info)
+	return &typeInfo{pkg: pkg, info: info}
+}
+
+// withTypeChecker returns a copy of the Tagger that type checks files with c
+func (t *Tagger) withTypeChecker(c *typeChecker) *Tagger {
+	checked := *t
+	checked.checker = c
+	return &checked
+}
+
+// withTypes returns a copy of the Tagger that uses the type information ti
+func (t *Tagger) withTypes(ti *typeInfo) *Tagger {
+	typed := *t
+	typed.types = ti
+	return &typed
+}
+
+// typeOf returns the type of the expression e, or nil if the Tagger has no type information or the type could not be
+// resolved
+func (t *Tagger) typeOf(e ast.Expr) types.Type {
+	if t.types == nil || e == nil {
+		return nil
+	}
+	typ := t.types.info.TypeOf(e)
+	if typ == nil || typ == types.Typ[types.Invalid] {
+		return nil
+	}
+	return typ
+}
+
+// qualifier returns the qualifier used to write types, which leaves out the package being tagged and writes every
+// other package by its import path, as in time.Time
+func (t *Tagger) qualifier() types.Qualifier {
+	if t.types == nil {
+		return nil
+	}
+	return types.RelativeTo(t.types.pkg)
+}
+
+// IsNillableType checks if the underlying type is a pointer, slice, map, or interface, so that named types such as
+// type Labels map[string]string are nillable as well
+func IsNillableType(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Interface:
+		return true
+	}
+	return false
+}
+
+// Is64BitIntegerType checks if the underlying type is int64 or uint64
+func Is64BitIntegerType(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && (b.Kind() == types.Int64 || b.Kind() == types.Uint64)
+}
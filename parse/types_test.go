@@ -0,0 +1,121 @@
+package parse
+
+import (
+	"go/ast"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTypes(t *testing.T) {
+	Convey("Given a package with named types declared in another file", t, func() {
+		dir, err := ioutil.TempDir(tempDir, "types")
+		So(err, ShouldBeNil)
+		err = ioutil.WriteFile(filepath.Join(dir, "types.go"), []byte(`package models
+
+type Labels map[string]string
+
+type Count int64
+`), 0664)
+		So(err, ShouldBeNil)
+		err = ioutil.WriteFile(filepath.Join(dir, "other.go"), []byte("package other\n"), 0664)
+		So(err, ShouldBeNil)
+		path := filepath.Join(dir, "user.go")
+		src := `package models
+
+import "time"
+
+type User struct {
+	Labels    Labels
+	Total     Count
+	CreatedAt time.Time
+	Missing   unknown.Type
+}
+`
+		err = ioutil.WriteFile(path, []byte(src), 0664)
+		So(err, ShouldBeNil)
+
+		opts := DefaultOptions()
+		opts.OptionRules, err = parseOptionRules("omitempty,string")
+		So(err, ShouldBeNil)
+		opts.Templates, err = parseTemplates([]string{`format:"{{if eq .TypeName \"time.Time\"}}rfc3339{{end}}"`})
+		So(err, ShouldBeNil)
+
+		Convey("Without types only the syntax of each field is used, and empty templates are not written", func() {
+			// time.Time is written as it is resolved, so the template matches it without types
+			data, err := NewTagger(opts, Filters{}).ProcessFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package models
+
+import "time"
+
+type User struct {
+	Labels    Labels       %sjson:"labels"%s
+	Total     Count        %sjson:"total"%s
+	CreatedAt time.Time    %sjson:"created_at" format:"rfc3339"%s
+	Missing   unknown.Type %sjson:"missing"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("With types the resolved types are used by option rules and templates", func() {
+			opts.Types = true
+			data, err := NewTagger(opts, Filters{}).ProcessFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, strings.Replace(`package models
+
+import "time"
+
+type User struct {
+	Labels    Labels       %sjson:"labels,omitempty"%s
+	Total     Count        %sjson:"total,string"%s
+	CreatedAt time.Time    %sjson:"created_at" format:"rfc3339"%s
+	Missing   unknown.Type %sjson:"missing"%s
+}
+`, "%s", "`", -1))
+		})
+
+		Convey("Templates are given the resolved type", func() {
+			file, ti, err := newTypeChecker().check([]byte(src), path)
+			So(err, ShouldBeNil)
+			tagger := NewTagger(opts, Filters{}).withTypes(ti)
+			var f *ast.Field
+			ast.Inspect(file, func(n ast.Node) bool {
+				if field, ok := n.(*ast.Field); ok && field.Names[0].Name == "Labels" {
+					f = field
+				}
+				return f == nil
+			})
+			So(f, ShouldNotBeNil)
+			d := NewTemplateData("User", "Labels", f)
+			d.SetType(tagger.typeOf(f.Type), tagger.qualifier())
+			So(d.TypeName, ShouldEqual, "Labels")
+			So(d.Underlying, ShouldEqual, "map[string]string")
+			So(d.IsMap, ShouldBeTrue)
+		})
+
+		Convey("The files of a package are parsed and type checked once per run", func() {
+			c := newTypeChecker()
+			file, ti, err := c.check([]byte(src), path)
+			So(err, ShouldBeNil)
+			types, err := ioutil.ReadFile(filepath.Join(dir, "types.go"))
+			So(err, ShouldBeNil)
+			other, shared, err := c.check(types, filepath.Join(dir, "types.go"))
+			So(err, ShouldBeNil)
+			So(shared == ti, ShouldBeTrue)
+			So(c.fset.File(file.Pos()) != nil, ShouldBeTrue)
+			So(c.fset.File(other.Pos()) != nil, ShouldBeTrue)
+			So(newTypeChecker().fset.File(file.Pos()) == nil, ShouldBeTrue)
+
+			Convey("Source that is not the file on disk is checked on its own with the files beside it", func() {
+				edited, eti, err := c.check([]byte(strings.Replace(src, "Count", "Labels", 1)), path)
+				So(err, ShouldBeNil)
+				So(eti != ti && edited != file, ShouldBeTrue)
+				So(eti.pkg.Scope().Lookup("Count") != nil, ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -4,13 +4,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 )
 
 var (
 	// Verbose determines whether or not Printf will print anything
 	Verbose = false
+	// Warnings is where Warnf prints warnings
+	Warnings io.Writer = os.Stderr
 	// ErrNoPathsGiven is returned when no paths to any .go files were provided at the command line
 	ErrNoPathsGiven = errors.New("No paths to any .go files were provided.")
+	// ErrStdinWithPaths is returned when - is given at the command line to read from stdin along with other paths
+	ErrStdinWithPaths = errors.New("No other paths may be provided when reading from stdin with -.")
+	// ErrNoTagsGiven is returned when the list of tags provided at the command line is empty
+	ErrNoTagsGiven = errors.New("No tags were provided.")
 )
 
 // ErrMutuallyExclusiveParameters takes two inputs and returns a canned error response
@@ -18,6 +27,111 @@ func ErrMutuallyExclusiveParameters(p, p2 string) error {
 	return fmt.Errorf("Mutually exclusive parameters provided: %s and %s", p, p2)
 }
 
+// ErrUnknownCase returns an error for a case that st does not support
+func ErrUnknownCase(c string) error {
+	return fmt.Errorf("Unknown case provided: %s", c)
+}
+
+// ErrUnknownOptionRule returns an error for an option rule that st does not support
+func ErrUnknownOptionRule(r string) error {
+	return fmt.Errorf("Unknown option rule provided: %s", r)
+}
+
+// ErrUnknownPreset returns an error for a preset that is neither built in nor defined in the config file
+func ErrUnknownPreset(p string) error {
+	return fmt.Errorf("Unknown preset provided: %s", p)
+}
+
+// ErrUnknownEmbeddedPolicy returns an error for an embedded field policy that st does not support
+func ErrUnknownEmbeddedPolicy(p string) error {
+	return fmt.Errorf("Unknown embedded field policy provided: %s, must be one of skip, name or inline", p)
+}
+
+// ErrUnknownMultiNamePolicy returns an error for a multi-name field policy that st does not support
+func ErrUnknownMultiNamePolicy(p string) error {
+	return fmt.Errorf("Unknown multi-name field policy provided: %s, must be one of report or split", p)
+}
+
+// ErrInvalidTemplate returns an error for a tag template that cannot be parsed or executed
+func ErrInvalidTemplate(t, reason string) error {
+	return fmt.Errorf("Invalid template provided: %s: %s", t, reason)
+}
+
+// ErrInvalidConfig returns an error for a config file that cannot be read or has invalid settings
+func ErrInvalidConfig(path, reason string) error {
+	return fmt.Errorf("Invalid config file %s: %s", path, reason)
+}
+
+// ErrInvalidDirective returns an error for a comment directive at pos, in the format file:line:col, that is malformed
+func ErrInvalidDirective(pos, directive, reason string) error {
+	return fmt.Errorf("%s: Invalid directive %s: %s", pos, directive, reason)
+}
+
+// ErrInvalidKeypair returns an error for a keypair that is not in the Struct.Field format
+func ErrInvalidKeypair(k string) error {
+	return fmt.Errorf("Invalid keypair provided: %s, must be in the format Struct.Field", k)
+}
+
+// ErrInvalidKeyMapping returns an error for a key mapping given to a flag that is not in the format the flag expects
+func ErrInvalidKeyMapping(m, format string) error {
+	return fmt.Errorf("Invalid key mapping provided: %s, must be in the format %s", m, format)
+}
+
+// ErrFilesNotTagged returns an error for check mode, when n files would be changed by tagging them
+func ErrFilesNotTagged(n int) error {
+	return fmt.Errorf("%d file(s) are not tagged", n)
+}
+
+// ErrInvalidEdit returns an error for an edit that is out of range for a source of the given length
+func ErrInvalidEdit(edit string, length int) error {
+	return fmt.Errorf("Edit is out of range for a source of length %d: %s", length, edit)
+}
+
+// ErrOverlappingEdits returns an error for two edits that change the same part of a source
+func ErrOverlappingEdits(edit, other string) error {
+	return fmt.Errorf("Overlapping edits: %s and %s", edit, other)
+}
+
+// ErrEditMismatch returns an error for an edit whose old text does not match the source
+func ErrEditMismatch(edit, found string) error {
+	return fmt.Errorf("Edit does not match the source, found %q: %s", found, edit)
+}
+
+// ErrMalformedTag returns an error for a struct tag that does not follow the key:"value" convention
+func ErrMalformedTag(tag string) error {
+	return fmt.Errorf("Malformed struct tag: %s", tag)
+}
+
+// MultiError collects the errors that occurred while processing a set of files. Total is the number of files that were
+// processed, and is used to summarize the errors.
+type MultiError struct {
+	Errors []error
+	Total  int
+}
+
+// Add adds an error to the MultiError
+func (m *MultiError) Add(err error) {
+	m.Errors = append(m.Errors, err)
+}
+
+// ErrorOrNil returns nil if no errors were added, otherwise it returns the MultiError
+func (m *MultiError) ErrorOrNil() error {
+	if len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
+
+// Error returns every error on its own line, followed by a summary
+func (m *MultiError) Error() string {
+	lines := make([]string, 0, len(m.Errors)+1)
+	for _, err := range m.Errors {
+		lines = append(lines, err.Error())
+	}
+	lines = append(lines, fmt.Sprintf("%d of %d files failed", len(m.Errors), m.Total))
+	return strings.Join(lines, "\n")
+}
+
 // Printf prints a string depending on verbosity... should be in a debug package?
 func Printf(s string, args ...interface{}) {
 	if Verbose {
@@ -25,6 +139,11 @@ func Printf(s string, args ...interface{}) {
 	}
 }
 
+// Warnf prints a warning to Warnings whether or not Verbose is set
+func Warnf(s string, args ...interface{}) {
+	fmt.Fprintf(Warnings, s, args...)
+}
+
 type HttpError struct {
 	Err  string `json:"error"`
 	Code int    `json:"status_code"`
@@ -1,6 +1,7 @@
 package sterrors
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -68,6 +69,93 @@ func TestErrors(t *testing.T) {
 		con.So(err.Error(), con.ShouldEqual, "Mutually exclusive parameters provided: 1 and 2")
 	})
 
+	con.Convey("Unknown case returns an error in the format we expect", t, func() {
+		err := ErrUnknownCase("shouting")
+		con.So(err.Error(), con.ShouldEqual, "Unknown case provided: shouting")
+	})
+
+	con.Convey("Unknown option rule returns an error in the format we expect", t, func() {
+		err := ErrUnknownOptionRule("required")
+		con.So(err.Error(), con.ShouldEqual, "Unknown option rule provided: required")
+	})
+
+	con.Convey("Unknown preset returns an error in the format we expect", t, func() {
+		err := ErrUnknownPreset("django")
+		con.So(err.Error(), con.ShouldEqual, "Unknown preset provided: django")
+	})
+
+	con.Convey("Stdin with other paths returns an error in the format we expect", t, func() {
+		con.So(ErrStdinWithPaths.Error(), con.ShouldEqual, "No other paths may be provided when reading from stdin with -.")
+	})
+
+	con.Convey("Unknown embedded policy returns an error in the format we expect", t, func() {
+		err := ErrUnknownEmbeddedPolicy("flatten")
+		con.So(err.Error(), con.ShouldEqual, "Unknown embedded field policy provided: flatten, must be one of skip, name or inline")
+	})
+
+	con.Convey("Unknown multi-name policy returns an error in the format we expect", t, func() {
+		err := ErrUnknownMultiNamePolicy("join")
+		con.So(err.Error(), con.ShouldEqual, "Unknown multi-name field policy provided: join, must be one of report or split")
+	})
+
+	con.Convey("Warnings are printed whether or not verbose is set", t, func() {
+		var buf bytes.Buffer
+		Warnings = &buf
+		defer func() { Warnings = os.Stderr }()
+		Verbose = false
+		Warnf("%s:%d: warning\n", "test.go", 4)
+		con.So(buf.String(), con.ShouldEqual, "test.go:4: warning\n")
+	})
+
+	con.Convey("Invalid template returns an error in the format we expect", t, func() {
+		err := ErrInvalidTemplate("gorm", "must be a single key:\"template\" pair")
+		con.So(err.Error(), con.ShouldEqual, "Invalid template provided: gorm: must be a single key:\"template\" pair")
+	})
+
+	con.Convey("Invalid config returns an error in the format we expect", t, func() {
+		err := ErrInvalidConfig(".st.json", "unexpected EOF")
+		con.So(err.Error(), con.ShouldEqual, "Invalid config file .st.json: unexpected EOF")
+	})
+
+	con.Convey("Invalid directive returns an error in the format we expect", t, func() {
+		err := ErrInvalidDirective("test.go:3:1", "//@st -case=upper", "Unknown case provided: upper")
+		con.So(err.Error(), con.ShouldEqual, "test.go:3:1: Invalid directive //@st -case=upper: Unknown case provided: upper")
+	})
+
+	con.Convey("Invalid keypair returns an error in the format we expect", t, func() {
+		err := ErrInvalidKeypair("Password")
+		con.So(err.Error(), con.ShouldEqual, "Invalid keypair provided: Password, must be in the format Struct.Field")
+	})
+
+	con.Convey("Invalid key mapping returns an error in the format we expect", t, func() {
+		err := ErrInvalidKeyMapping("db", "old:new")
+		con.So(err.Error(), con.ShouldEqual, "Invalid key mapping provided: db, must be in the format old:new")
+	})
+
+	con.Convey("Files not tagged returns an error in the format we expect", t, func() {
+		err := ErrFilesNotTagged(2)
+		con.So(err.Error(), con.ShouldEqual, "2 file(s) are not tagged")
+	})
+
+	con.Convey("Edit errors return errors in the format we expect", t, func() {
+		con.So(ErrInvalidEdit("10:12", 5).Error(), con.ShouldEqual, "Edit is out of range for a source of length 5: 10:12")
+		con.So(ErrOverlappingEdits("1:5", "3:4").Error(), con.ShouldEqual, "Overlapping edits: 1:5 and 3:4")
+		con.So(ErrEditMismatch("1:2", "x").Error(), con.ShouldEqual, `Edit does not match the source, found "x": 1:2`)
+	})
+
+	con.Convey("Malformed tag returns an error in the format we expect", t, func() {
+		con.So(ErrMalformedTag(`json:name`).Error(), con.ShouldEqual, "Malformed struct tag: json:name")
+	})
+
+	con.Convey("A MultiError lists every error followed by a summary", t, func() {
+		m := &MultiError{Total: 3}
+		con.So(m.ErrorOrNil(), con.ShouldBeNil)
+		m.Add(errors.New("a.go:1:1: first"))
+		m.Add(errors.New("b.go:2:1: second"))
+		con.So(m.ErrorOrNil(), con.ShouldEqual, m)
+		con.So(m.Error(), con.ShouldEqual, "a.go:1:1: first\nb.go:2:1: second\n2 of 3 files failed")
+	})
+
 	con.Convey("We can test http formatting", t, func() {
 		testErr := errors.New("Test error")
 		errBytes := FormatHTTPError(testErr, 400)